@@ -0,0 +1,112 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SignatureHeader carries the hex-encoded HMAC-SHA256 of the request body, prefixed with "sha256=".
+const SignatureHeader = "X-Signature-256"
+
+const (
+	maxAttempts    = 5
+	initialBackoff = time.Second
+	requestTimeout = 10 * time.Second
+)
+
+// Log defines an interface for logging.
+type Log interface {
+	Info(string, ...zap.Field)
+	Error(string, ...zap.Field)
+}
+
+// Notifier delivers alert notifications to a webhook.
+type Notifier struct {
+	url     string
+	secret  []byte
+	client  *http.Client
+	log     Log
+	backoff time.Duration
+}
+
+// NewNotifier creates a Notifier posting to url and signing requests with secret.
+func NewNotifier(url, secret string, log Log) *Notifier {
+	return &Notifier{
+		url:     url,
+		secret:  []byte(secret),
+		client:  &http.Client{Timeout: requestTimeout},
+		log:     log,
+		backoff: initialBackoff,
+	}
+}
+
+// Notify posts payload as JSON to the webhook. Network errors, 429 and 5xx
+// responses are retried with exponential backoff up to maxAttempts times; other
+// responses end delivery. Delivery stops early when ctx is done.
+func (n *Notifier) Notify(ctx context.Context, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	signature := n.sign(body)
+
+	backoff := n.backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := n.post(ctx, body, signature)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt == maxAttempts {
+			return fmt.Errorf("failed to deliver notification after %d attempts: %w", attempt, err)
+		}
+
+		n.log.Info("Retrying notification", zap.Int("attempt", attempt), zap.Duration("backoff", backoff), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post sends a single request and reports whether a failure is worth retrying.
+func (n *Notifier) post(ctx context.Context, body []byte, signature string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, signature)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("webhook responded with %s", resp.Status)
+	default:
+		return false, fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+}
+
+// sign returns the signature header value of body.
+func (n *Notifier) sign(body []byte) string {
+	mac := hmac.New(sha256.New, n.secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
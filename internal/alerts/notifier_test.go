@@ -0,0 +1,145 @@
+package alerts
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// newTestNotifier returns a Notifier posting to a test server that answers with
+// the given statuses in turn, repeating the last one, and counts the requests.
+func newTestNotifier(t *testing.T, statuses ...int) (*Notifier, *atomic.Int32) {
+	t.Helper()
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		w.WriteHeader(statuses[min(n, len(statuses))-1])
+	}))
+	t.Cleanup(srv.Close)
+
+	n := NewNotifier(srv.URL, "secret", zap.NewNop())
+	n.backoff = time.Millisecond
+	return n, &calls
+}
+
+func TestNotifySignsBody(t *testing.T) {
+	var body []byte
+	var signature string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	n := NewNotifier(srv.URL, "secret", zap.NewNop())
+	if err := n.Notify(context.Background(), map[string]int{"upload_id": 7}); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	if string(body) != `{"upload_id":7}` {
+		t.Errorf("body = %s, want the JSON payload", body)
+	}
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); signature != want {
+		t.Errorf("%s = %q, want %q", SignatureHeader, signature, want)
+	}
+}
+
+func TestNotifyRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		calls    int
+		wantErr  bool
+	}{
+		{
+			name:     "success",
+			statuses: []int{http.StatusOK},
+			calls:    1,
+		},
+		{
+			name:     "server error then success",
+			statuses: []int{http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusOK},
+			calls:    3,
+		},
+		{
+			name:     "too many requests then success",
+			statuses: []int{http.StatusTooManyRequests, http.StatusAccepted},
+			calls:    2,
+		},
+		{
+			name:     "server error every time",
+			statuses: []int{http.StatusBadGateway},
+			calls:    maxAttempts,
+			wantErr:  true,
+		},
+		{
+			name:     "client error",
+			statuses: []int{http.StatusBadRequest},
+			calls:    1,
+			wantErr:  true,
+		},
+		{
+			name:     "server error then client error",
+			statuses: []int{http.StatusInternalServerError, http.StatusNotFound},
+			calls:    2,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, calls := newTestNotifier(t, tt.statuses...)
+
+			err := n.Notify(context.Background(), "payload")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Notify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := int(calls.Load()); got != tt.calls {
+				t.Errorf("webhook called %d times, want %d", got, tt.calls)
+			}
+		})
+	}
+}
+
+func TestNotifyStopsWhenCancelled(t *testing.T) {
+	n, calls := newTestNotifier(t, http.StatusServiceUnavailable)
+	n.backoff = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- n.Notify(ctx, "payload")
+	}()
+
+	// Wait for the first attempt before cancelling the backoff
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Notify() error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Notify() did not return after ctx was cancelled")
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("webhook called %d times, want 1", got)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/drstein77/priceanalyzer/internal/alerts"
 	"github.com/drstein77/priceanalyzer/internal/config"
 	"github.com/drstein77/priceanalyzer/internal/controllers"
 	"github.com/drstein77/priceanalyzer/internal/dbkeeper"
@@ -17,9 +18,10 @@ import (
 )
 
 type Server struct {
-	srv *http.Server
-	ctx context.Context
-	Log *logger.Logger
+	srv     *http.Server
+	storage *storage.MemoryStorage
+	ctx     context.Context
+	Log     *logger.Logger
 }
 
 // NewServer creates a new Server instance with the provided context
@@ -48,10 +50,23 @@ func (server *Server) Serve() {
 	defer keeper.Close()
 
 	// initialize the storage instance
-	memoryStorage := initializeStorage(server.ctx, keeper, nLogger)
+	memoryStorage := initializeStorage(server.ctx, keeper, nLogger,
+		storage.CategoryPolicy(option.UnknownCategories()),
+		storage.OutlierCheck{
+			Action:    storage.OutlierAction(option.OutlierAction()),
+			Threshold: option.OutlierThreshold(),
+		},
+		initializeNotifier(option.AlertWebhookURL(), option.AlertSecret(), nLogger),
+	)
 	if memoryStorage == nil {
 		nLogger.Debug("Failed to initialize storage")
 	}
+	server.storage = memoryStorage
+
+	// purge the trash in the background
+	if memoryStorage != nil && option.TrashRetention() > 0 {
+		go memoryStorage.PurgeTrash(server.ctx, option.TrashRetention())
+	}
 
 	// create a new controller to process incoming requests
 	basecontr := initializeBaseController(server.ctx, memoryStorage, nLogger)
@@ -79,8 +94,23 @@ func initializeKeeper(ctx context.Context, dataBaseDSN func() string, logger *lo
 }
 
 // initializeStorage initializes a MemoryStorage instance
-func initializeStorage(ctx context.Context, keeper storage.Keeper, logger *logger.Logger) *storage.MemoryStorage {
-	return storage.NewMemoryStorage(ctx, keeper, logger)
+func initializeStorage(ctx context.Context, keeper storage.Keeper, logger *logger.Logger,
+	categoryPolicy storage.CategoryPolicy, outlierCheck storage.OutlierCheck, notifier storage.Notifier,
+) *storage.MemoryStorage {
+	return storage.NewMemoryStorage(ctx, keeper, logger, categoryPolicy, outlierCheck, notifier)
+}
+
+// initializeNotifier initializes the alert webhook Notifier, or returns nil when no webhook
+// is configured or it has no signing secret
+func initializeNotifier(url, secret string, logger *logger.Logger) storage.Notifier {
+	if url == "" {
+		return nil
+	}
+	if secret == "" {
+		logger.Error("alert webhook secret is empty, alert delivery is disabled")
+		return nil
+	}
+	return alerts.NewNotifier(url, secret, logger)
 }
 
 // initializeBaseController initializes a BaseController instance
@@ -142,5 +172,12 @@ func (server *Server) Shutdown(timeout time.Duration) {
 		}
 		server.Log.Info("server stopped")
 	}
+
+	// wait for the queued alert notifications
+	if server.storage != nil {
+		if err := server.storage.Close(ctxShutDown); err != nil {
+			log.Printf("alert delivery did not finish: %s", err)
+		}
+	}
 	server.Log.Info("server exited properly")
 }
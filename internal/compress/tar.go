@@ -3,9 +3,13 @@ package compress
 import (
 	"archive/tar"
 	"bytes"
+	"compress/gzip"
 	"errors"
+	"fmt"
 	"io"
+	"path"
 	"strings"
+	"time"
 )
 
 // TarReader implements io.ReadCloser for reading the content of a CSV file from a TAR archive.
@@ -65,3 +69,110 @@ func (t *TarReader) Read(p []byte) (int, error) {
 func (t *TarReader) Close() error {
 	return nil // Required to satisfy the io.ReadCloser interface
 }
+
+// tarPartSize is the size from which buffered content is written out as a part.
+const tarPartSize = 1 << 20
+
+// TarWriter implements packaging data into a TAR archive.
+// A TAR header carries the size of its entry, so the content is buffered in memory
+// and written as soon as tarPartSize bytes are available, cut after the last full
+// line. Content that fits in one part is archived under the file name; longer
+// content is archived as numbered parts (data.part0001.csv, data.part0002.csv, ...)
+// whose concatenation is the file.
+type TarWriter struct {
+	tarWriter *tar.Writer
+	fileName  string
+	buf       bytes.Buffer
+	parts     int
+}
+
+// NewTarWriter creates a new TarWriter with the specified file name inside the archive.
+func NewTarWriter(w io.Writer, fileName string) (*TarWriter, error) {
+	return &TarWriter{
+		tarWriter: tar.NewWriter(w),
+		fileName:  fileName,
+	}, nil
+}
+
+// Write writes data to a file inside the TAR archive.
+func (t *TarWriter) Write(p []byte) (int, error) {
+	n, _ := t.buf.Write(p)
+	for t.buf.Len() > tarPartSize {
+		// Cut after the last line end of the part, or at its size for a longer line
+		size := bytes.LastIndexByte(t.buf.Bytes()[:tarPartSize], '\n') + 1
+		if size == 0 {
+			size = tarPartSize
+		}
+		if err := t.writePart(t.buf.Next(size)); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// Close writes the buffered content into the archive and closes it.
+func (t *TarWriter) Close() error {
+	if t.parts == 0 {
+		if err := t.writeEntry(t.fileName, t.buf.Bytes()); err != nil {
+			return err
+		}
+	} else if t.buf.Len() > 0 {
+		if err := t.writePart(t.buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	t.buf.Reset()
+	return t.tarWriter.Close()
+}
+
+// writePart archives the next numbered part of the file.
+func (t *TarWriter) writePart(content []byte) error {
+	t.parts++
+	ext := path.Ext(t.fileName)
+	name := fmt.Sprintf("%s.part%04d%s", strings.TrimSuffix(t.fileName, ext), t.parts, ext)
+	return t.writeEntry(name, content)
+}
+
+func (t *TarWriter) writeEntry(name string, content []byte) error {
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0o644,
+		Size:     int64(len(content)),
+		ModTime:  time.Now(),
+	}
+	if err := t.tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := t.tarWriter.Write(content); err != nil {
+		return err
+	}
+	return t.tarWriter.Flush()
+}
+
+// TarGzWriter implements packaging data into a gzip-compressed TAR archive.
+type TarGzWriter struct {
+	*TarWriter
+	gzipWriter *gzip.Writer
+}
+
+// NewTarGzWriter creates a new TarGzWriter with the specified file name inside the archive.
+func NewTarGzWriter(w io.Writer, fileName string) (*TarGzWriter, error) {
+	gw := gzip.NewWriter(w)
+	tw, err := NewTarWriter(gw, fileName)
+	if err != nil {
+		return nil, err
+	}
+	return &TarGzWriter{
+		TarWriter:  tw,
+		gzipWriter: gw,
+	}, nil
+}
+
+// Close closes the TAR archive and the gzip stream.
+func (t *TarGzWriter) Close() error {
+	if err := t.TarWriter.Close(); err != nil {
+		return err
+	}
+	return t.gzipWriter.Close()
+}
@@ -0,0 +1,184 @@
+package compress
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+type tarEntry struct {
+	name    string
+	content string
+}
+
+func readTarEntries(t *testing.T, r io.Reader) []tarEntry {
+	t.Helper()
+	var entries []tarEntry
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return entries
+		}
+		if err != nil {
+			t.Fatalf("read tar header: %v", err)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("read tar entry %s: %v", header.Name, err)
+		}
+		entries = append(entries, tarEntry{name: header.Name, content: string(content)})
+	}
+}
+
+// csvLines returns CSV content of at least size bytes made of numbered lines.
+func csvLines(size int) string {
+	var sb strings.Builder
+	sb.WriteString("id,name\n")
+	for i := 1; sb.Len() < size; i++ {
+		fmt.Fprintf(&sb, "%d,product %d\n", i, i)
+	}
+	return sb.String()
+}
+
+func TestTarWriter(t *testing.T) {
+	large := csvLines(2*tarPartSize + tarPartSize/2)
+
+	tests := []struct {
+		name      string
+		gzip      bool
+		content   string
+		wantNames []string
+	}{
+		{name: "empty", content: "", wantNames: []string{"prices.csv"}},
+		{name: "small", content: "id,name\n1,Milk\n", wantNames: []string{"prices.csv"}},
+		{
+			name:      "parts",
+			content:   large,
+			wantNames: []string{"prices.part0001.csv", "prices.part0002.csv", "prices.part0003.csv"},
+		},
+		{name: "gzip small", gzip: true, content: "id,name\n1,Milk\n", wantNames: []string{"prices.csv"}},
+		{
+			name:      "gzip parts",
+			gzip:      true,
+			content:   large,
+			wantNames: []string{"prices.part0001.csv", "prices.part0002.csv", "prices.part0003.csv"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			var w io.WriteCloser
+			var err error
+			if tt.gzip {
+				w, err = NewTarGzWriter(&buf, "prices.csv")
+			} else {
+				w, err = NewTarWriter(&buf, "prices.csv")
+			}
+			if err != nil {
+				t.Fatalf("create writer: %v", err)
+			}
+
+			// Write in small chunks, as an export does row by row
+			content := tt.content
+			for len(content) > 0 {
+				n := min(len(content), 4096)
+				if _, err := io.WriteString(w, content[:n]); err != nil {
+					t.Fatalf("write: %v", err)
+				}
+				content = content[n:]
+			}
+			if !tt.gzip && len(tt.wantNames) > 1 && buf.Len() == 0 {
+				t.Error("nothing was written before Close")
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("close: %v", err)
+			}
+
+			var r io.Reader = &buf
+			if tt.gzip {
+				gr, err := gzip.NewReader(&buf)
+				if err != nil {
+					t.Fatalf("open gzip stream: %v", err)
+				}
+				r = gr
+			}
+
+			entries := readTarEntries(t, r)
+			if len(entries) != len(tt.wantNames) {
+				t.Fatalf("got %d entries, want %d", len(entries), len(tt.wantNames))
+			}
+			var joined strings.Builder
+			for i, entry := range entries {
+				if entry.name != tt.wantNames[i] {
+					t.Errorf("entry %d: got name %q, want %q", i, entry.name, tt.wantNames[i])
+				}
+				if len(entry.content) > tarPartSize {
+					t.Errorf("entry %s holds %d bytes, more than a part", entry.name, len(entry.content))
+				}
+				if i < len(entries)-1 && !strings.HasSuffix(entry.content, "\n") {
+					t.Errorf("entry %s is not cut at a line end", entry.name)
+				}
+				joined.WriteString(entry.content)
+			}
+			if joined.String() != tt.content {
+				t.Error("entries do not add up to the written content")
+			}
+		})
+	}
+}
+
+func TestTarWriterLongLine(t *testing.T) {
+	line := strings.Repeat("x", tarPartSize+10)
+
+	var buf bytes.Buffer
+	w, _ := NewTarWriter(&buf, "prices.csv")
+	if _, err := io.WriteString(w, line); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	entries := readTarEntries(t, &buf)
+	if len(entries) != 2 || len(entries[0].content) != tarPartSize || entries[0].content+entries[1].content != line {
+		t.Errorf("a line longer than a part is not cut at the part size")
+	}
+}
+
+func TestTarReader(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, entry := range []tarEntry{{"readme.txt", "skip me"}, {"prices.csv", "id,name\n1,Milk\n"}} {
+		if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: entry.name, Mode: 0o644, Size: int64(len(entry.content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(tw, entry.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewTarReader(io.NopCloser(&buf))
+	if err != nil {
+		t.Fatalf("NewTarReader: %v", err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(got) != "id,name\n1,Milk\n" {
+		t.Errorf("got %q", got)
+	}
+
+	if _, err := NewTarReader(io.NopCloser(bytes.NewReader(nil))); err == nil {
+		t.Error("expected an error for an archive without a CSV file")
+	}
+}
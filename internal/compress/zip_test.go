@@ -0,0 +1,71 @@
+package compress
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestZipWriter(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "empty", content: ""},
+		{name: "small", content: "id,name\n1,Milk\n"},
+		{name: "large", content: csvLines(2 * tarPartSize)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewZipWriter(&buf, "prices.csv")
+			if err != nil {
+				t.Fatalf("create writer: %v", err)
+			}
+			if _, err := io.WriteString(w, tt.content); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("close: %v", err)
+			}
+
+			zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			if err != nil {
+				t.Fatalf("open archive: %v", err)
+			}
+			if len(zr.File) != 1 || zr.File[0].Name != "prices.csv" {
+				t.Fatalf("unexpected entries: %v", zr.File)
+			}
+
+			r, err := NewZipReader(io.NopCloser(&buf))
+			if err != nil {
+				t.Fatalf("NewZipReader: %v", err)
+			}
+			defer r.Close()
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			if string(got) != tt.content {
+				t.Error("archived content differs from the written content")
+			}
+		})
+	}
+}
+
+func TestZipReaderWithoutCSV(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	if _, err := zw.Create("readme.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewZipReader(io.NopCloser(&buf)); err == nil {
+		t.Error("expected an error for an archive without a CSV file")
+	}
+}
@@ -3,16 +3,25 @@ package config
 import (
 	"flag"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Options struct {
-	runAddr     string
-	logLevel    string
-	dataBaseDSN string
+	runAddr           string
+	logLevel          string
+	dataBaseDSN       string
+	trashRetention    time.Duration
+	unknownCategories string
+	outlierAction     string
+	outlierThreshold  float64
+	alertWebhookURL   string
+	alertSecret       string
 }
 
 func NewOptions() *Options {
@@ -29,9 +38,24 @@ func (o *Options) ParseFlags() {
 	regStringVar(&o.runAddr, "a", getEnvOrDefault("RUN_ADDRESS", ":8080"), "address and port to run server")
 	regStringVar(&o.logLevel, "l", getEnvOrDefault("LOG_LEVEL", "debug"), "log level")
 	regStringVar(&o.dataBaseDSN, "d", getEnvOrDefault("DATABASE_URI", ""), "database connection string")
+	regDurationVar(&o.trashRetention, "t", getEnvDurationOrDefault("TRASH_RETENTION", 30*24*time.Hour),
+		"how long deleted prices are kept before purging, 0 disables purging")
+	regStringVar(&o.unknownCategories, "c", getEnvOrDefault("UNKNOWN_CATEGORIES", "create"),
+		"what ingestion does with unknown categories: create or reject")
+	regStringVar(&o.outlierAction, "o", getEnvOrDefault("OUTLIER_ACTION", "off"),
+		"what ingestion does with prices deviating from the current price: off, flag or reject")
+	regFloatVar(&o.outlierThreshold, "p", getEnvFloatOrDefault("OUTLIER_THRESHOLD", 100),
+		"largest accepted deviation from the current price during ingestion, in percent")
+	regStringVar(&o.alertWebhookURL, "w", getEnvOrDefault("ALERT_WEBHOOK_URL", ""),
+		"webhook receiving price alerts, empty disables delivery")
+	regStringVar(&o.alertSecret, "s", getEnvOrDefault("ALERT_WEBHOOK_SECRET", ""), "key signing price alert webhooks, required for delivery")
 
 	// parse the arguments passed to the server into registered variables
 	flag.Parse()
+
+	if math.IsNaN(o.outlierThreshold) || math.IsInf(o.outlierThreshold, 0) || o.outlierThreshold <= 0 {
+		log.Fatalf("Invalid outlier threshold %v, expected a positive number of percent", o.outlierThreshold)
+	}
 }
 
 func (o *Options) RunAddr() string {
@@ -46,10 +70,42 @@ func (o *Options) DataBaseDSN() string {
 	return o.dataBaseDSN
 }
 
+func (o *Options) TrashRetention() time.Duration {
+	return o.trashRetention
+}
+
+func (o *Options) UnknownCategories() string {
+	return o.unknownCategories
+}
+
+func (o *Options) OutlierAction() string {
+	return o.outlierAction
+}
+
+func (o *Options) OutlierThreshold() float64 {
+	return o.outlierThreshold
+}
+
+func (o *Options) AlertWebhookURL() string {
+	return o.alertWebhookURL
+}
+
+func (o *Options) AlertSecret() string {
+	return o.alertSecret
+}
+
 func regStringVar(p *string, name string, value string, usage string) {
 	flag.StringVar(p, name, value, usage)
 }
 
+func regDurationVar(p *time.Duration, name string, value time.Duration, usage string) {
+	flag.DurationVar(p, name, value, usage)
+}
+
+func regFloatVar(p *float64, name string, value float64, usage string) {
+	flag.Float64Var(p, name, value, usage)
+}
+
 // getEnvOrDefault reads an environment variable or returns a default value if the variable is not set or is empty.
 func getEnvOrDefault(key string, defaultValue string) string {
 	if value, exists := os.LookupEnv(key); exists && value != "" {
@@ -58,6 +114,36 @@ func getEnvOrDefault(key string, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvDurationOrDefault reads a duration from an environment variable or returns a default value
+// if the variable is not set, is empty or cannot be parsed.
+func getEnvDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	value := getEnvOrDefault(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration %q in %s, using %s", value, key, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
+
+// getEnvFloatOrDefault reads a number from an environment variable or returns a default value
+// if the variable is not set, is empty or cannot be parsed.
+func getEnvFloatOrDefault(key string, defaultValue float64) float64 {
+	value := getEnvOrDefault(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Invalid number %q in %s, using %v", value, key, defaultValue)
+		return defaultValue
+	}
+	return number
+}
+
 // loadEnvFile loads environment variables from a .env file
 func loadEnvFile() {
 	// Determine the path to the .env file relative to the current working directory
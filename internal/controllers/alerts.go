@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"github.com/drstein77/priceanalyzer/internal/storage"
+	"github.com/go-chi/chi"
+)
+
+func (h *BaseController) listAlertRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.storage.ListAlertRules(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve alert rules: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, rules)
+}
+
+func (h *BaseController) getAlertRule(w http.ResponseWriter, r *http.Request) {
+	id, ok := alertRuleID(w, r)
+	if !ok {
+		return
+	}
+
+	rule, err := h.storage.GetAlertRule(r.Context(), id)
+	if err != nil {
+		writeAlertError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rule)
+}
+
+func (h *BaseController) postAlertRule(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var input models.AlertRule
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&input); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid alert rule: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.storage.CreateAlertRule(r.Context(), input)
+	if err != nil {
+		writeAlertError(w, err)
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/api/v0/alerts/%d", rule.ID))
+	writeJSON(w, http.StatusCreated, rule)
+}
+
+func (h *BaseController) deleteAlertRule(w http.ResponseWriter, r *http.Request) {
+	id, ok := alertRuleID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.storage.DeleteAlertRule(r.Context(), id); err != nil {
+		writeAlertError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// alertRuleID reads the id URL parameter, answering 400 if it is not an integer.
+func alertRuleID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Alert rule id must be an integer", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// writeAlertError maps storage errors to HTTP status codes.
+func writeAlertError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrInvalidAlert):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, storage.ErrNotFound):
+		http.Error(w, "Alert rule not found", http.StatusNotFound)
+	default:
+		http.Error(w, fmt.Sprintf("Failed to process alert rule: %v", err), http.StatusInternalServerError)
+	}
+}
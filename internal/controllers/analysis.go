@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"github.com/drstein77/priceanalyzer/internal/storage"
+)
+
+func (h *BaseController) getOutliers(w http.ResponseWriter, r *http.Request) {
+	query, err := parseOutlierQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	outliers, err := h.storage.FindOutliers(r.Context(), query)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to find outliers: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, outliers)
+}
+
+// parseOutlierQuery reads the filter, methods, z, k, deviation and limit query parameters.
+func parseOutlierQuery(values url.Values) (models.OutlierQuery, error) {
+	var query models.OutlierQuery
+
+	filter, err := parseFilter(values)
+	if err != nil {
+		return query, err
+	}
+	zScore, err := parseFloat(values, "z")
+	if err != nil {
+		return query, err
+	}
+	iqrFactor, err := parseFloat(values, "k")
+	if err != nil {
+		return query, err
+	}
+	deviation, err := parseFloat(values, "deviation")
+	if err != nil {
+		return query, err
+	}
+	limit, err := parseInt(values, "limit")
+	if err != nil {
+		return query, err
+	}
+
+	if raw := values.Get("methods"); raw != "" {
+		query.Methods = strings.Split(raw, ",")
+	}
+	if zScore != nil {
+		query.ZScore = *zScore
+	}
+	if iqrFactor != nil {
+		query.IQRFactor = *iqrFactor
+	}
+	if deviation != nil {
+		query.Deviation = *deviation
+	}
+	query.Filter = filter
+	query.Limit = limit
+	return query, nil
+}
+
+func (h *BaseController) getTrend(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	window, err := parseInt(r.URL.Query(), "window")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	trend, err := h.storage.GetTrend(r.Context(), models.TrendQuery{
+		Filter:   filter,
+		Interval: r.URL.Query().Get("interval"),
+		TimeZone: r.URL.Query().Get("tz"),
+		Window:   window,
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to compute trend: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, trend)
+}
+
+func (h *BaseController) getForecast(w http.ResponseWriter, r *http.Request) {
+	query, err := parseForecastQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	forecast, err := h.storage.GetForecast(r.Context(), query)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to compute forecast: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, forecast)
+}
+
+// parseForecastQuery reads the filter, model, periods, season and confidence query parameters.
+func parseForecastQuery(values url.Values) (models.ForecastQuery, error) {
+	var query models.ForecastQuery
+
+	filter, err := parseFilter(values)
+	if err != nil {
+		return query, err
+	}
+	periods, err := parseInt(values, "periods")
+	if err != nil {
+		return query, err
+	}
+	season, err := parseInt(values, "season")
+	if err != nil {
+		return query, err
+	}
+	confidence, err := parseFloat(values, "confidence")
+	if err != nil {
+		return query, err
+	}
+
+	if confidence != nil {
+		query.Confidence = *confidence
+	}
+	query.Filter = filter
+	query.Model = values.Get("model")
+	query.Periods = periods
+	query.Season = season
+	return query, nil
+}
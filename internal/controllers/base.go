@@ -3,13 +3,18 @@ package controllers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 
+	"github.com/drstein77/priceanalyzer/internal/export"
 	"github.com/drstein77/priceanalyzer/internal/middleware"
 	"github.com/drstein77/priceanalyzer/internal/models"
+	"github.com/drstein77/priceanalyzer/internal/storage"
 	"github.com/go-chi/chi"
+	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
@@ -17,11 +22,48 @@ import (
 type Storage interface {
 	ProcessPrices(context.Context, io.Reader) (*models.ProcessResponse, error)
 	GetAllProducts(context.Context) ([]models.Product, error)
+	ListProducts(context.Context, models.ListQuery) (*models.ProductPage, error)
+	StreamProducts(context.Context, models.ProductFilter, func(models.Product) error) error
+	GetProduct(context.Context, int, bool) (*models.Product, error)
+	CreateProduct(context.Context, models.ProductInput) (*models.Product, error)
+	ReplaceProduct(context.Context, int, models.ProductInput) (*models.Product, error)
+	PatchProduct(context.Context, int, models.ProductInput) (*models.Product, error)
+	DeleteProduct(context.Context, int) error
+	RestoreProduct(context.Context, int) (*models.Product, error)
+	GetPriceHistory(context.Context, int) (*models.PriceHistory, error)
+	DeleteProducts(context.Context, models.ProductFilter) (*models.BulkResult, error)
+	UpdateProducts(context.Context, models.ProductFilter, models.BulkUpdate) (*models.BulkResult, error)
+	GetStats(context.Context, models.ProductFilter) (*models.ProcessResponse, error)
+	FindOutliers(context.Context, models.OutlierQuery) ([]models.Outlier, error)
+	GetTrend(context.Context, models.TrendQuery) (*models.Trend, error)
+	GetForecast(context.Context, models.ForecastQuery) (*models.Forecast, error)
+	GetCategoryStats(context.Context, models.ProductFilter, bool) ([]models.CategoryStats, error)
+	DiffPrices(context.Context, models.DiffQuery) (*models.PriceDiff, error)
+	GetHistogram(context.Context, models.HistogramQuery) (*models.Histogram, error)
+	ComparePeriods(context.Context, models.CompareQuery) ([]models.CategoryComparison, error)
+	TopProducts(context.Context, models.TopQuery) ([]models.RankedProduct, error)
+	GetTimeSeries(context.Context, models.TimeSeriesQuery) (*models.TimeSeries, error)
+	ListBaskets(context.Context) ([]models.Basket, error)
+	GetBasket(context.Context, string) (*models.Basket, error)
+	CreateBasket(context.Context, models.Basket) (*models.Basket, error)
+	UpdateBasket(context.Context, string, models.Basket) (*models.Basket, error)
+	DeleteBasket(context.Context, string) error
+	GetPriceIndex(context.Context, models.IndexQuery) (*models.PriceIndex, error)
+	ListAlertRules(context.Context) ([]models.AlertRule, error)
+	GetAlertRule(context.Context, int) (*models.AlertRule, error)
+	CreateAlertRule(context.Context, models.AlertRule) (*models.AlertRule, error)
+	DeleteAlertRule(context.Context, int) error
+	ListCategories(context.Context) ([]models.Category, error)
+	GetCategory(context.Context, int) (*models.Category, error)
+	CreateCategory(context.Context, models.Category) (*models.Category, error)
+	UpdateCategory(context.Context, int, models.Category) (*models.Category, error)
+	DeleteCategory(context.Context, int) error
 }
 
 // Log interface for logging
 type Log interface {
 	Info(string, ...zapcore.Field)
+	Error(string, ...zapcore.Field)
 }
 
 // BaseController struct for handling requests
@@ -54,14 +96,62 @@ func (h *BaseController) Route() *chi.Mux {
 	r.Group(func(r chi.Router) {
 		r.Use(middleware.CompressResponseMiddleware)
 		r.Get("/api/v0/prices", h.getPrices)
+		r.Get("/api/v0/diff/export", h.exportDiff)
+		r.Get("/api/v0/stats/compare/export", h.exportComparison)
 	})
 
+	r.Delete("/api/v0/prices", h.deletePrices)
+	r.Patch("/api/v0/prices", h.patchPrices)
+	r.Get("/api/v0/prices/list", h.listPrices)
+	r.Get("/api/v0/diff", h.getDiff)
+	r.Get("/api/v0/stats", h.getStats)
+	r.Get("/api/v0/stats/categories", h.getCategoryStats)
+	r.Get("/api/v0/stats/timeseries", h.getTimeSeries)
+	r.Get("/api/v0/stats/histogram", h.getHistogram)
+	r.Get("/api/v0/stats/top", h.getTopProducts)
+	r.Get("/api/v0/stats/compare", h.getComparison)
+
+	r.Post("/api/v0/prices/item", h.postProduct)
+	r.Get("/api/v0/prices/{id}", h.getProduct)
+	r.Put("/api/v0/prices/{id}", h.putProduct)
+	r.Patch("/api/v0/prices/{id}", h.patchProduct)
+	r.Delete("/api/v0/prices/{id}", h.deleteProduct)
+	r.Post("/api/v0/prices/{id}/restore", h.restoreProduct)
+
+	r.Get("/api/v0/products/{id}/history", h.getPriceHistory)
+
+	r.Get("/api/v0/analysis/outliers", h.getOutliers)
+	r.Get("/api/v0/analysis/trend", h.getTrend)
+	r.Get("/api/v0/analysis/forecast", h.getForecast)
+
+	r.Get("/api/v0/baskets", h.listBaskets)
+	r.Post("/api/v0/baskets", h.postBasket)
+	r.Get("/api/v0/baskets/{basket}", h.getBasket)
+	r.Put("/api/v0/baskets/{basket}", h.putBasket)
+	r.Delete("/api/v0/baskets/{basket}", h.deleteBasket)
+	r.Get("/api/v0/index/{basket}", h.getPriceIndex)
+
+	r.Get("/api/v0/alerts", h.listAlertRules)
+	r.Post("/api/v0/alerts", h.postAlertRule)
+	r.Get("/api/v0/alerts/{id}", h.getAlertRule)
+	r.Delete("/api/v0/alerts/{id}", h.deleteAlertRule)
+
+	r.Get("/api/v0/categories", h.listCategories)
+	r.Post("/api/v0/categories", h.postCategory)
+	r.Get("/api/v0/categories/{id}", h.getCategory)
+	r.Put("/api/v0/categories/{id}", h.putCategory)
+	r.Delete("/api/v0/categories/{id}", h.deleteCategory)
+
 	return r
 }
 
 func (h *BaseController) postPrices(w http.ResponseWriter, r *http.Request) {
 	response, err := h.storage.ProcessPrices(r.Context(), r.Body)
 	if err != nil {
+		if errors.Is(err, storage.ErrUnknownCategory) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, fmt.Sprintf("Failed to process prices: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -75,17 +165,72 @@ func (h *BaseController) postPrices(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *BaseController) getPrices(w http.ResponseWriter, r *http.Request) {
-	products, err := h.storage.GetAllProducts(r.Context())
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts, err := parseExportOptions(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Encode the data in the negotiated export format
+	format := export.FromContext(r.Context())
+	w.Header().Set("Content-Type", format.ContentType)
+
+	writer, err := format.NewWriter(w, opts)
 	if err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+
+	// Large exports outlive the server write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		h.log.Info("Failed to clear write deadline", zap.Error(err))
+	}
+
+	// Rows are encoded as they are read, so the response starts with the first one
+	started := false
+	err = h.storage.StreamProducts(r.Context(), filter, func(product models.Product) error {
+		started = true
+		return writer.Write(product)
+	})
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		if !started {
+			http.Error(w, fmt.Sprintf("Failed to retrieve prices: %v", err), http.StatusInternalServerError)
+			return
+		}
+		// Part of the archive is already on the wire, so drop the connection
+		// rather than let the client take a truncated file for a complete one
+		h.log.Error("Failed to stream prices", zap.Error(err))
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func (h *BaseController) listPrices(w http.ResponseWriter, r *http.Request) {
+	query, err := parseListQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.storage.ListProducts(r.Context(), query)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, fmt.Sprintf("Failed to retrieve prices: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	// Set the Content-Type header
 	w.Header().Set("Content-Type", "application/json")
-
-	// Encode the data to JSON and send the response
-	if err := json.NewEncoder(w).Encode(products); err != nil {
+	if err := json.NewEncoder(w).Encode(page); err != nil {
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 	}
 }
@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"go.uber.org/zap"
+)
+
+var testProducts = []models.Product{
+	{ID: 1, Name: "Milk", Category: "dairy", Price: 1.5, CreatedAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+	{ID: 2, Name: "Bread", Category: "bakery", Price: 2, CreatedAt: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)},
+}
+
+var errStream = errors.New("connection reset")
+
+// streamStorage streams a fixed set of products and fails with err
+// once failAfter products have been sent, if err is set.
+type streamStorage struct {
+	Storage
+	products  []models.Product
+	err       error
+	failAfter int
+}
+
+func (s *streamStorage) StreamProducts(_ context.Context, _ models.ProductFilter, fn func(models.Product) error) error {
+	for i, product := range s.products {
+		if s.err != nil && i == s.failAfter {
+			return s.err
+		}
+		if err := fn(product); err != nil {
+			return err
+		}
+	}
+	if s.err != nil && s.failAfter >= len(s.products) {
+		return s.err
+	}
+	return nil
+}
+
+func TestGetPrices(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "csv",
+			query:      "type=none",
+			wantStatus: http.StatusOK,
+			wantBody:   "id,name,category,price,create_date\n1,Milk,dairy,1.50,2024-01-02\n2,Bread,bakery,2.00,2024-01-03\n",
+		},
+		{
+			name:       "fields and rename",
+			query:      "type=none&fields=name,price&rename=price:cost",
+			wantStatus: http.StatusOK,
+			wantBody:   "name,cost\nMilk,1.50\nBread,2.00\n",
+		},
+		{
+			name:       "without header",
+			query:      "type=none&fields=id&header=false",
+			wantStatus: http.StatusOK,
+			wantBody:   "1\n2\n",
+		},
+		{
+			name:       "ndjson without header",
+			query:      "type=none&format=ndjson&fields=id,name&header=false",
+			wantStatus: http.StatusOK,
+			wantBody:   "[1,\"Milk\"]\n[2,\"Bread\"]\n",
+		},
+		{
+			name:       "json with rename",
+			query:      "type=none&format=json&fields=id&rename=id:sku",
+			wantStatus: http.StatusOK,
+			wantBody:   "[{\"sku\":1},{\"sku\":2}]\n",
+		},
+		{name: "unknown field", query: "fields=weight", wantStatus: http.StatusBadRequest},
+		{name: "invalid rename", query: "rename=price", wantStatus: http.StatusBadRequest},
+		{name: "duplicate header", query: "rename=name:id", wantStatus: http.StatusBadRequest},
+		{name: "invalid header flag", query: "header=maybe", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewBaseController(context.Background(), &streamStorage{products: testProducts}, zap.NewNop())
+			req := httptest.NewRequest(http.MethodGet, "/api/v0/prices?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			h.Route().ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("got body %q, want %q", rec.Body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestGetPricesStreamErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		failAfter int
+		wantAbort bool
+	}{
+		{name: "before the first row", query: "type=none", failAfter: 0},
+		{name: "before the first row in an archive", query: "type=tar.gz", failAfter: 0},
+		{name: "after the first row", query: "type=none", failAfter: 1, wantAbort: true},
+		{name: "after the first row in an archive", query: "type=zip", failAfter: 1, wantAbort: true},
+		{name: "after the last row", query: "type=tar", failAfter: 2, wantAbort: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &streamStorage{products: testProducts, err: errStream, failAfter: tt.failAfter}
+			h := NewBaseController(context.Background(), storage, zap.NewNop())
+			req := httptest.NewRequest(http.MethodGet, "/api/v0/prices?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			aborted := func() (aborted bool) {
+				defer func() {
+					if p := recover(); p != nil {
+						if p != http.ErrAbortHandler {
+							panic(p)
+						}
+						aborted = true
+					}
+				}()
+				h.Route().ServeHTTP(rec, req)
+				return false
+			}()
+
+			if aborted != tt.wantAbort {
+				t.Fatalf("aborted = %v, want %v", aborted, tt.wantAbort)
+			}
+			if !tt.wantAbort && rec.Code != http.StatusInternalServerError {
+				t.Errorf("got status %d, want %d", rec.Code, http.StatusInternalServerError)
+			}
+		})
+	}
+}
+
+func TestGetPricesAbortDropsConnection(t *testing.T) {
+	// Enough rows to put part of the file on the wire before the failure
+	products := make([]models.Product, 10000)
+	for i := range products {
+		products[i] = models.Product{ID: i + 1, Name: "Milk", Category: "dairy", Price: 1.5}
+	}
+	storage := &streamStorage{products: products, err: errStream, failAfter: len(products) - 1}
+	h := NewBaseController(context.Background(), storage, zap.NewNop())
+
+	server := httptest.NewServer(h.Route())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/v0/prices?type=none")
+	if err != nil {
+		t.Fatalf("request failed before the response started: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if _, err := io.ReadAll(resp.Body); err == nil {
+		t.Error("a truncated export was delivered as a complete response")
+	}
+}
@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"github.com/drstein77/priceanalyzer/internal/storage"
+	"github.com/go-chi/chi"
+)
+
+func (h *BaseController) listBaskets(w http.ResponseWriter, r *http.Request) {
+	baskets, err := h.storage.ListBaskets(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve baskets: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, baskets)
+}
+
+func (h *BaseController) getBasket(w http.ResponseWriter, r *http.Request) {
+	basket, err := h.storage.GetBasket(r.Context(), chi.URLParam(r, "basket"))
+	if err != nil {
+		writeBasketError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, basket)
+}
+
+func (h *BaseController) postBasket(w http.ResponseWriter, r *http.Request) {
+	input, ok := decodeBasket(w, r)
+	if !ok {
+		return
+	}
+
+	basket, err := h.storage.CreateBasket(r.Context(), input)
+	if err != nil {
+		writeBasketError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/api/v0/baskets/"+url.PathEscape(basket.Name))
+	writeJSON(w, http.StatusCreated, basket)
+}
+
+func (h *BaseController) putBasket(w http.ResponseWriter, r *http.Request) {
+	input, ok := decodeBasket(w, r)
+	if !ok {
+		return
+	}
+
+	basket, err := h.storage.UpdateBasket(r.Context(), chi.URLParam(r, "basket"), input)
+	if err != nil {
+		writeBasketError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, basket)
+}
+
+func (h *BaseController) deleteBasket(w http.ResponseWriter, r *http.Request) {
+	if err := h.storage.DeleteBasket(r.Context(), chi.URLParam(r, "basket")); err != nil {
+		writeBasketError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *BaseController) getPriceIndex(w http.ResponseWriter, r *http.Request) {
+	base, err := parsePeriod(r.URL.Query(), "base")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	index, err := h.storage.GetPriceIndex(r.Context(), models.IndexQuery{
+		Basket:   chi.URLParam(r, "basket"),
+		Base:     base,
+		Interval: r.URL.Query().Get("interval"),
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeBasketError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, index)
+}
+
+// parsePeriod reads a required period given as a month (YYYY-MM) or a date.
+func parsePeriod(values url.Values, name string) (time.Time, error) {
+	raw := values.Get(name)
+	if raw == "" {
+		return time.Time{}, fmt.Errorf("%s is required", name)
+	}
+	if month, err := time.Parse("2006-01", raw); err == nil {
+		return month, nil
+	}
+	date, err := time.Parse(dateLayout, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s: expected YYYY-MM or YYYY-MM-DD", name)
+	}
+	return date, nil
+}
+
+// decodeBasket reads a JSON basket from the request body, answering 400 if it is malformed.
+func decodeBasket(w http.ResponseWriter, r *http.Request) (models.Basket, bool) {
+	defer r.Body.Close()
+
+	var input models.Basket
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&input); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid basket: %v", err), http.StatusBadRequest)
+		return input, false
+	}
+	return input, true
+}
+
+// writeBasketError maps storage errors to HTTP status codes.
+func writeBasketError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrInvalidBasket):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, storage.ErrNotFound):
+		http.Error(w, "Basket not found", http.StatusNotFound)
+	case errors.Is(err, storage.ErrConflict):
+		http.Error(w, "A basket with this name already exists", http.StatusConflict)
+	default:
+		http.Error(w, fmt.Sprintf("Failed to process basket: %v", err), http.StatusInternalServerError)
+	}
+}
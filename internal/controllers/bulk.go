@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"github.com/drstein77/priceanalyzer/internal/storage"
+)
+
+func (h *BaseController) deletePrices(w http.ResponseWriter, r *http.Request) {
+	filter, ok := bulkFilter(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.storage.DeleteProducts(r.Context(), filter)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete prices: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h *BaseController) patchPrices(w http.ResponseWriter, r *http.Request) {
+	filter, ok := bulkFilter(w, r)
+	if !ok {
+		return
+	}
+
+	defer r.Body.Close()
+	var update models.BulkUpdate
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&update); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid update: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.storage.UpdateProducts(r.Context(), filter, update)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidUpdate) || errors.Is(err, storage.ErrUnknownCategory) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, storage.ErrConflict) {
+			http.Error(w, "The update would duplicate existing prices", http.StatusConflict)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to update prices: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// bulkFilter reads the filter of a bulk operation, which must be confirmed with confirm=true.
+func bulkFilter(w http.ResponseWriter, r *http.Request) (models.ProductFilter, bool) {
+	values := r.URL.Query()
+	if values.Get("confirm") != "true" {
+		http.Error(w, "Bulk operations must be confirmed with confirm=true", http.StatusBadRequest)
+		return models.ProductFilter{}, false
+	}
+
+	filter, err := parseFilter(values)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return models.ProductFilter{}, false
+	}
+	if filter.AsOf != nil {
+		http.Error(w, "as_of cannot be used with bulk operations", http.StatusBadRequest)
+		return models.ProductFilter{}, false
+	}
+	return filter, true
+}
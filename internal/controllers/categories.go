@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"github.com/drstein77/priceanalyzer/internal/storage"
+	"github.com/go-chi/chi"
+)
+
+func (h *BaseController) listCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.storage.ListCategories(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve categories: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, categories)
+}
+
+func (h *BaseController) getCategory(w http.ResponseWriter, r *http.Request) {
+	id, ok := categoryID(w, r)
+	if !ok {
+		return
+	}
+
+	category, err := h.storage.GetCategory(r.Context(), id)
+	if err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, category)
+}
+
+func (h *BaseController) postCategory(w http.ResponseWriter, r *http.Request) {
+	input, ok := decodeCategory(w, r)
+	if !ok {
+		return
+	}
+
+	category, err := h.storage.CreateCategory(r.Context(), input)
+	if err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/api/v0/categories/%d", category.ID))
+	writeJSON(w, http.StatusCreated, category)
+}
+
+func (h *BaseController) putCategory(w http.ResponseWriter, r *http.Request) {
+	id, ok := categoryID(w, r)
+	if !ok {
+		return
+	}
+	input, ok := decodeCategory(w, r)
+	if !ok {
+		return
+	}
+
+	category, err := h.storage.UpdateCategory(r.Context(), id, input)
+	if err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, category)
+}
+
+func (h *BaseController) deleteCategory(w http.ResponseWriter, r *http.Request) {
+	id, ok := categoryID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.storage.DeleteCategory(r.Context(), id); err != nil {
+		if errors.Is(err, storage.ErrConflict) {
+			http.Error(w, "Category has subcategories or prices", http.StatusConflict)
+			return
+		}
+		writeCategoryError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// categoryID reads the id URL parameter, answering 400 if it is not an integer.
+func categoryID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Category id must be an integer", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// decodeCategory reads a JSON category from the request body, answering 400 if it is malformed.
+func decodeCategory(w http.ResponseWriter, r *http.Request) (models.Category, bool) {
+	defer r.Body.Close()
+
+	var input models.Category
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&input); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid category: %v", err), http.StatusBadRequest)
+		return input, false
+	}
+	return input, true
+}
+
+// writeCategoryError maps storage errors to HTTP status codes.
+func writeCategoryError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrInvalidCategory):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, storage.ErrNotFound):
+		http.Error(w, "Category not found", http.StatusNotFound)
+	case errors.Is(err, storage.ErrConflict):
+		http.Error(w, "Category name or alias is already in use", http.StatusConflict)
+	default:
+		http.Error(w, fmt.Sprintf("Failed to process category: %v", err), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/drstein77/priceanalyzer/internal/export"
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"github.com/drstein77/priceanalyzer/internal/storage"
+	"go.uber.org/zap"
+)
+
+func (h *BaseController) getComparison(w http.ResponseWriter, r *http.Request) {
+	comparisons, ok := h.comparePeriods(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, comparisons)
+}
+
+// exportComparison writes the period comparison as CSV, packaged by CompressResponseMiddleware.
+func (h *BaseController) exportComparison(w http.ResponseWriter, r *http.Request) {
+	if format := export.FromContext(r.Context()); format.Name != export.CSV.Name {
+		http.Error(w, fmt.Sprintf("Period comparisons cannot be exported as %s", format.Name), http.StatusBadRequest)
+		return
+	}
+
+	comparisons, ok := h.comparePeriods(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", export.CSV.ContentType)
+	if err := export.WriteComparisonCSV(w, comparisons); err != nil {
+		h.log.Error("Failed to write period comparison", zap.Error(err))
+	}
+}
+
+// comparePeriods compares the periods named by the query parameters, answering with an error if that fails.
+func (h *BaseController) comparePeriods(w http.ResponseWriter, r *http.Request) ([]models.CategoryComparison, bool) {
+	query, err := parseCompareQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	comparisons, err := h.storage.ComparePeriods(r.Context(), query)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+		http.Error(w, fmt.Sprintf("Failed to compare periods: %v", err), http.StatusInternalServerError)
+		return nil, false
+	}
+	return comparisons, true
+}
+
+// parseCompareQuery reads the filter and the a_start, a_end, b_start and b_end query parameters.
+func parseCompareQuery(values url.Values) (models.CompareQuery, error) {
+	var query models.CompareQuery
+
+	filter, err := parseFilter(values)
+	if err != nil {
+		return query, err
+	}
+	a, err := parsePeriodRange(values, "a")
+	if err != nil {
+		return query, err
+	}
+	b, err := parsePeriodRange(values, "b")
+	if err != nil {
+		return query, err
+	}
+
+	query.Filter = filter
+	query.A = a
+	query.B = b
+	return query, nil
+}
+
+// parsePeriodRange reads the required <prefix>_start and <prefix>_end dates of a period.
+// The end date is included in the period.
+func parsePeriodRange(values url.Values, prefix string) (models.Period, error) {
+	var period models.Period
+
+	start, err := parseDate(values, prefix+"_start")
+	if err != nil {
+		return period, err
+	}
+	end, err := parseMoment(values, prefix+"_end")
+	if err != nil {
+		return period, err
+	}
+	if start == nil || end == nil {
+		return period, fmt.Errorf("%[1]s_start and %[1]s_end are required", prefix)
+	}
+
+	period.Start = *start
+	period.End = *end
+	return period, nil
+}
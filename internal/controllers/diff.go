@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/drstein77/priceanalyzer/internal/export"
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"github.com/drstein77/priceanalyzer/internal/storage"
+	"go.uber.org/zap"
+)
+
+func (h *BaseController) getDiff(w http.ResponseWriter, r *http.Request) {
+	diff, ok := h.diffPrices(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, diff)
+}
+
+// exportDiff writes the price changes as CSV, packaged by CompressResponseMiddleware.
+func (h *BaseController) exportDiff(w http.ResponseWriter, r *http.Request) {
+	if format := export.FromContext(r.Context()); format.Name != export.CSV.Name {
+		http.Error(w, fmt.Sprintf("Price changes cannot be exported as %s", format.Name), http.StatusBadRequest)
+		return
+	}
+
+	diff, ok := h.diffPrices(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", export.CSV.ContentType)
+	if err := export.WritePriceChangesCSV(w, diff.Changes); err != nil {
+		h.log.Error("Failed to write price changes", zap.Error(err))
+	}
+}
+
+// diffPrices compares the snapshots named by the query parameters, answering with an error if that fails.
+func (h *BaseController) diffPrices(w http.ResponseWriter, r *http.Request) (*models.PriceDiff, bool) {
+	query, err := parseDiffQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	diff, err := h.storage.DiffPrices(r.Context(), query)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+		http.Error(w, fmt.Sprintf("Failed to compare prices: %v", err), http.StatusInternalServerError)
+		return nil, false
+	}
+	return diff, true
+}
+
+// parseDiffQuery reads the from, to, sort and order query parameters.
+// Changes are ordered by descending magnitude unless asked otherwise.
+func parseDiffQuery(values url.Values) (models.DiffQuery, error) {
+	var query models.DiffQuery
+
+	from, err := parseDiffSide(values, "from")
+	if err != nil {
+		return query, err
+	}
+	to, err := parseDiffSide(values, "to")
+	if err != nil {
+		return query, err
+	}
+
+	switch order := values.Get("order"); order {
+	case "", "desc":
+		query.Desc = true
+	case "asc":
+	default:
+		return query, fmt.Errorf("invalid order %q: expected asc or desc", order)
+	}
+
+	query.From = from
+	query.To = to
+	query.Sort = values.Get("sort")
+	return query, nil
+}
+
+// parseDiffSide reads a snapshot given either as an upload id or as a date or timestamp.
+func parseDiffSide(values url.Values, name string) (models.DiffSide, error) {
+	var side models.DiffSide
+
+	raw := values.Get(name)
+	if raw == "" {
+		return side, fmt.Errorf("%s is required", name)
+	}
+	if uploadID, err := strconv.Atoi(raw); err == nil {
+		side.UploadID = &uploadID
+		return side, nil
+	}
+
+	asOf, err := parseMoment(values, name)
+	if err != nil {
+		return side, fmt.Errorf("invalid %s: expected an upload id, YYYY-MM-DD or an RFC 3339 timestamp", name)
+	}
+	side.AsOf = asOf
+	return side, nil
+}
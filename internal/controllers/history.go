@@ -0,0 +1,27 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/drstein77/priceanalyzer/internal/storage"
+)
+
+func (h *BaseController) getPriceHistory(w http.ResponseWriter, r *http.Request) {
+	id, ok := productID(w, r)
+	if !ok {
+		return
+	}
+
+	history, err := h.storage.GetPriceHistory(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "Product not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to retrieve price history: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, history)
+}
@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drstein77/priceanalyzer/internal/export"
+	"github.com/drstein77/priceanalyzer/internal/models"
+)
+
+const dateLayout = "2006-01-02"
+
+// parseFilter reads the start, end, min, max, category, product_id, include_deleted and as_of query parameters.
+func parseFilter(values url.Values) (models.ProductFilter, error) {
+	var filter models.ProductFilter
+
+	start, err := parseDate(values, "start")
+	if err != nil {
+		return filter, err
+	}
+	end, err := parseDate(values, "end")
+	if err != nil {
+		return filter, err
+	}
+	minPrice, err := parseFloat(values, "min")
+	if err != nil {
+		return filter, err
+	}
+	maxPrice, err := parseFloat(values, "max")
+	if err != nil {
+		return filter, err
+	}
+	includeDeleted, err := parseBool(values, "include_deleted")
+	if err != nil {
+		return filter, err
+	}
+	asOf, err := parseMoment(values, "as_of")
+	if err != nil {
+		return filter, err
+	}
+	productID, err := parseInt(values, "product_id")
+	if err != nil {
+		return filter, err
+	}
+
+	filter.Start = start
+	filter.End = end
+	filter.Min = minPrice
+	filter.Max = maxPrice
+	filter.Category = values.Get("category")
+	if productID != 0 {
+		filter.ProductID = &productID
+	}
+	filter.IncludeDeleted = includeDeleted
+	filter.AsOf = asOf
+	return filter, nil
+}
+
+// parseListQuery reads the filter, sort, order, limit and cursor query parameters.
+func parseListQuery(values url.Values) (models.ListQuery, error) {
+	var query models.ListQuery
+
+	filter, err := parseFilter(values)
+	if err != nil {
+		return query, err
+	}
+	limit, err := parseInt(values, "limit")
+	if err != nil {
+		return query, err
+	}
+
+	switch order := values.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		query.Desc = true
+	default:
+		return query, fmt.Errorf("invalid order %q: expected asc or desc", order)
+	}
+
+	query.Filter = filter
+	query.Sort = values.Get("sort")
+	query.Limit = limit
+	query.Cursor = values.Get("cursor")
+	return query, nil
+}
+
+// parseExportOptions reads the fields, rename and header query parameters,
+// e.g. fields=name,price,create_date&rename=create_date:date&header=false.
+func parseExportOptions(values url.Values) (export.Options, error) {
+	var opts export.Options
+
+	var fields []string
+	if raw := values.Get("fields"); raw != "" {
+		fields = strings.Split(raw, ",")
+	}
+
+	headers := make(map[string]string)
+	if raw := values.Get("rename"); raw != "" {
+		for _, pair := range strings.Split(raw, ",") {
+			field, header, ok := strings.Cut(pair, ":")
+			if !ok {
+				return opts, fmt.Errorf("invalid rename %q: expected field:header", pair)
+			}
+			headers[field] = header
+		}
+	}
+
+	columns, err := export.NewColumns(fields, headers)
+	if err != nil {
+		return opts, err
+	}
+	opts.Columns = columns
+
+	if values.Get("header") != "" {
+		withHeader, err := parseBool(values, "header")
+		if err != nil {
+			return opts, err
+		}
+		opts.NoHeader = !withHeader
+	}
+
+	return opts, nil
+}
+
+func parseDate(values url.Values, name string) (*time.Time, error) {
+	raw := values.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	date, err := time.Parse(dateLayout, raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: expected YYYY-MM-DD", name)
+	}
+	return &date, nil
+}
+
+// parseMoment reads a point in time given as an RFC 3339 timestamp or a date.
+// A date stands for the end of that day.
+func parseMoment(values url.Values, name string) (*time.Time, error) {
+	raw := values.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	if moment, err := time.Parse(time.RFC3339, raw); err == nil {
+		return &moment, nil
+	}
+	date, err := time.Parse(dateLayout, raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: expected YYYY-MM-DD or an RFC 3339 timestamp", name)
+	}
+	moment := date.AddDate(0, 0, 1).Add(-time.Microsecond)
+	return &moment, nil
+}
+
+func parseFloat(values url.Values, name string) (*float64, error) {
+	raw := values.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	number, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: expected a number", name)
+	}
+	return &number, nil
+}
+
+func parseInt(values url.Values, name string) (int, error) {
+	raw := values.Get(name)
+	if raw == "" {
+		return 0, nil
+	}
+	number, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: expected an integer", name)
+	}
+	return number, nil
+}
+
+func parseBool(values url.Values, name string) (bool, error) {
+	raw := values.Get(name)
+	if raw == "" {
+		return false, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: expected true or false", name)
+	}
+	return value, nil
+}
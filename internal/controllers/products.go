@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"github.com/drstein77/priceanalyzer/internal/storage"
+	"github.com/go-chi/chi"
+)
+
+func (h *BaseController) getProduct(w http.ResponseWriter, r *http.Request) {
+	id, ok := productID(w, r)
+	if !ok {
+		return
+	}
+
+	includeDeleted, err := parseBool(r.URL.Query(), "include_deleted")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	product, err := h.storage.GetProduct(r.Context(), id, includeDeleted)
+	if err != nil {
+		writeProductError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, product)
+}
+
+func (h *BaseController) postProduct(w http.ResponseWriter, r *http.Request) {
+	input, ok := decodeProduct(w, r)
+	if !ok {
+		return
+	}
+
+	product, err := h.storage.CreateProduct(r.Context(), input)
+	if err != nil {
+		writeProductError(w, err)
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/api/v0/prices/%d", product.ID))
+	writeJSON(w, http.StatusCreated, product)
+}
+
+func (h *BaseController) putProduct(w http.ResponseWriter, r *http.Request) {
+	id, ok := productID(w, r)
+	if !ok {
+		return
+	}
+	input, ok := decodeProduct(w, r)
+	if !ok {
+		return
+	}
+
+	product, err := h.storage.ReplaceProduct(r.Context(), id, input)
+	if err != nil {
+		writeProductError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, product)
+}
+
+func (h *BaseController) patchProduct(w http.ResponseWriter, r *http.Request) {
+	id, ok := productID(w, r)
+	if !ok {
+		return
+	}
+	input, ok := decodeProduct(w, r)
+	if !ok {
+		return
+	}
+
+	product, err := h.storage.PatchProduct(r.Context(), id, input)
+	if err != nil {
+		writeProductError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, product)
+}
+
+func (h *BaseController) deleteProduct(w http.ResponseWriter, r *http.Request) {
+	id, ok := productID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.storage.DeleteProduct(r.Context(), id); err != nil {
+		writeProductError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *BaseController) restoreProduct(w http.ResponseWriter, r *http.Request) {
+	id, ok := productID(w, r)
+	if !ok {
+		return
+	}
+
+	product, err := h.storage.RestoreProduct(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, storage.ErrConflict) {
+			http.Error(w, "Product is not deleted or an identical product exists", http.StatusConflict)
+			return
+		}
+		writeProductError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, product)
+}
+
+// productID reads the id URL parameter, answering 400 if it is not an integer.
+func productID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Product id must be an integer", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// decodeProduct reads a JSON product from the request body, answering 400 if it is malformed.
+func decodeProduct(w http.ResponseWriter, r *http.Request) (models.ProductInput, bool) {
+	defer r.Body.Close()
+
+	var input models.ProductInput
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&input); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid product: %v", err), http.StatusBadRequest)
+		return input, false
+	}
+	return input, true
+}
+
+// writeProductError maps storage errors to HTTP status codes.
+func writeProductError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrInvalidProduct), errors.Is(err, storage.ErrUnknownCategory):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, storage.ErrNotFound):
+		http.Error(w, "Product not found", http.StatusNotFound)
+	case errors.Is(err, storage.ErrConflict):
+		http.Error(w, "An identical product already exists", http.StatusConflict)
+	default:
+		http.Error(w, fmt.Sprintf("Failed to process product: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// writeJSON encodes v as the JSON response body.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/drstein77/priceanalyzer/internal/export"
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"github.com/drstein77/priceanalyzer/internal/storage"
+	"go.uber.org/zap"
+)
+
+func (h *BaseController) getStats(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stats, err := h.storage.GetStats(r.Context(), filter)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve stats: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (h *BaseController) getCategoryStats(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rollup, err := parseBool(r.URL.Query(), "rollup")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	asCSV, err := wantsCSV(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stats, err := h.storage.GetCategoryStats(r.Context(), filter, rollup)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve category stats: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if !asCSV {
+		writeJSON(w, http.StatusOK, stats)
+		return
+	}
+
+	w.Header().Set("Content-Type", export.CSV.ContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+export.CSV.FileName("category_stats")+`"`)
+	if err := export.WriteCategoryStatsCSV(w, stats); err != nil {
+		h.log.Error("Failed to write category stats", zap.Error(err))
+	}
+}
+
+func (h *BaseController) getTimeSeries(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	series, err := h.storage.GetTimeSeries(r.Context(), models.TimeSeriesQuery{
+		Filter:   filter,
+		Interval: r.URL.Query().Get("interval"),
+		TimeZone: r.URL.Query().Get("tz"),
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to retrieve time series: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, series)
+}
+
+func (h *BaseController) getHistogram(w http.ResponseWriter, r *http.Request) {
+	query, err := parseHistogramQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	histogram, err := h.storage.GetHistogram(r.Context(), query)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to retrieve histogram: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, histogram)
+}
+
+// parseHistogramQuery reads the filter, buckets, edges and log query parameters.
+// Edges are given as a comma-separated list of prices.
+func parseHistogramQuery(values url.Values) (models.HistogramQuery, error) {
+	var query models.HistogramQuery
+
+	filter, err := parseFilter(values)
+	if err != nil {
+		return query, err
+	}
+	buckets, err := parseInt(values, "buckets")
+	if err != nil {
+		return query, err
+	}
+	logScale, err := parseBool(values, "log")
+	if err != nil {
+		return query, err
+	}
+
+	if raw := values.Get("edges"); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			edge, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil || math.IsNaN(edge) || math.IsInf(edge, 0) {
+				return query, fmt.Errorf("invalid edges: expected comma-separated finite numbers")
+			}
+			query.Edges = append(query.Edges, edge)
+		}
+	}
+
+	query.Filter = filter
+	query.Buckets = buckets
+	query.Log = logScale
+	return query, nil
+}
+
+func (h *BaseController) getTopProducts(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	n, err := parseInt(r.URL.Query(), "n")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The most expensive products come first unless asked otherwise
+	query := models.TopQuery{Filter: filter, N: n, Desc: true, Per: r.URL.Query().Get("per")}
+	switch order := r.URL.Query().Get("order"); order {
+	case "", "desc":
+	case "asc":
+		query.Desc = false
+	default:
+		http.Error(w, fmt.Sprintf("invalid order %q: expected asc or desc", order), http.StatusBadRequest)
+		return
+	}
+
+	ranking, err := h.storage.TopProducts(r.Context(), query)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to retrieve ranking: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, ranking)
+}
+
+// wantsCSV reports whether a report is requested as CSV rather than JSON,
+// either with format=csv or through the Accept header.
+func wantsCSV(r *http.Request) (bool, error) {
+	switch format := r.URL.Query().Get("format"); format {
+	case "csv":
+		return true, nil
+	case "json":
+		return false, nil
+	case "":
+	default:
+		return false, fmt.Errorf("unsupported report format %q", format)
+	}
+
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err == nil && mediaType == export.CSV.ContentType {
+			return true, nil
+		}
+	}
+	return false, nil
+}
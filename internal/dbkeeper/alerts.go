@@ -0,0 +1,175 @@
+package dbkeeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"github.com/drstein77/priceanalyzer/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+const alertRuleColumns = "id, name, product_id, category, condition, threshold::float8, created_at"
+
+// ListAlertRules returns all alert rules in creation order.
+func (kp *DBKeeper) ListAlertRules(ctx context.Context) ([]models.AlertRule, error) {
+	if kp.pool == nil {
+		return nil, fmt.Errorf("database connection pool is nil")
+	}
+
+	rows, err := kp.pool.Query(ctx, `SELECT `+alertRuleColumns+` FROM alert_rules ORDER BY id`)
+	if err != nil {
+		kp.log.Error("Failed to execute query", zap.Error(err))
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []models.AlertRule{}
+	for rows.Next() {
+		rule, err := scanAlertRule(rows)
+		if err != nil {
+			kp.log.Error("Failed to scan row", zap.Error(err))
+			return nil, err
+		}
+		rules = append(rules, *rule)
+	}
+
+	if rows.Err() != nil {
+		kp.log.Error("Error occurred during rows iteration", zap.Error(rows.Err()))
+		return nil, fmt.Errorf("error during rows iteration: %w", rows.Err())
+	}
+	return rules, nil
+}
+
+// GetAlertRule returns the alert rule with the given id or storage.ErrNotFound.
+func (kp *DBKeeper) GetAlertRule(ctx context.Context, id int) (*models.AlertRule, error) {
+	if kp.pool == nil {
+		return nil, fmt.Errorf("database connection pool is nil")
+	}
+	return scanAlertRule(kp.pool.QueryRow(ctx, `SELECT `+alertRuleColumns+` FROM alert_rules WHERE id = $1`, id))
+}
+
+// CreateAlertRule stores a new alert rule.
+func (kp *DBKeeper) CreateAlertRule(ctx context.Context, rule models.AlertRule) (*models.AlertRule, error) {
+	if kp.pool == nil {
+		return nil, fmt.Errorf("database connection pool is nil")
+	}
+
+	created, err := scanAlertRule(kp.pool.QueryRow(ctx, `
+		INSERT INTO alert_rules (name, product_id, category, condition, threshold)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+alertRuleColumns,
+		rule.Name, rule.ProductID, rule.Category, rule.Condition, rule.Threshold))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return nil, fmt.Errorf("%w: unknown product", storage.ErrInvalidAlert)
+		}
+		kp.log.Error("Failed to create alert rule", zap.Error(err))
+		return nil, err
+	}
+	return created, nil
+}
+
+// DeleteAlertRule removes the alert rule with the given id.
+func (kp *DBKeeper) DeleteAlertRule(ctx context.Context, id int) error {
+	if kp.pool == nil {
+		return fmt.Errorf("database connection pool is nil")
+	}
+
+	tag, err := kp.pool.Exec(ctx, `DELETE FROM alert_rules WHERE id = $1`, id)
+	if err != nil {
+		kp.log.Error("Failed to delete alert rule", zap.Error(err))
+		return fmt.Errorf("failed to delete alert rule: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// MatchAlerts returns the live prices of an upload that meet the condition of an alert rule.
+// A category rule also covers the aliases and subcategories of a managed category.
+// Percent conditions compare a price with the latest live price of the same product
+// dated no later than it outside the upload, and never match without one.
+func (kp *DBKeeper) MatchAlerts(ctx context.Context, uploadID int) ([]models.AlertMatch, error) {
+	if kp.pool == nil {
+		return nil, fmt.Errorf("database connection pool is nil")
+	}
+
+	rows, err := kp.pool.Query(ctx, `
+		SELECT rule.id, rule.name, r.product_id, r.name, r.category, r.id, r.price::float8,
+		       prev.price::float8,
+		       ROUND((r.price - prev.price) / NULLIF(prev.price, 0) * 100, 2)::float8
+		FROM prices r
+		LEFT JOIN LATERAL (
+			SELECT p.price
+			FROM prices p
+			WHERE p.product_id = r.product_id
+			  AND p.deleted_at IS NULL
+			  AND p.upload_id IS DISTINCT FROM r.upload_id
+			  AND p.create_date <= r.create_date
+			ORDER BY p.create_date DESC, p.id DESC
+			LIMIT 1
+		) prev ON TRUE
+		JOIN alert_rules rule
+		  ON (rule.product_id IS NULL OR rule.product_id = r.product_id)
+		 AND (rule.category IS NULL OR r.category = rule.category OR r.category IN (
+				WITH RECURSIVE tree AS (
+					SELECT id, name FROM categories
+					WHERE LOWER(name) = LOWER(rule.category)
+					   OR id = (SELECT category_id FROM category_aliases WHERE alias = LOWER(rule.category))
+					UNION ALL
+					SELECT c.id, c.name FROM categories c JOIN tree ON c.parent_id = tree.id
+				)
+				SELECT name FROM tree
+			))
+		WHERE r.upload_id = $1
+		  AND r.deleted_at IS NULL
+		  AND CASE rule.condition
+				WHEN 'above' THEN r.price > rule.threshold
+				WHEN 'below' THEN r.price < rule.threshold
+				WHEN 'rise_percent' THEN prev.price > 0 AND (r.price - prev.price) / prev.price * 100 > rule.threshold
+				WHEN 'drop_percent' THEN prev.price > 0 AND (prev.price - r.price) / prev.price * 100 > rule.threshold
+			  END
+		ORDER BY rule.id, r.id
+	`, uploadID)
+	if err != nil {
+		kp.log.Error("Failed to execute query", zap.Error(err))
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	matches := []models.AlertMatch{}
+	for rows.Next() {
+		var m models.AlertMatch
+		err := rows.Scan(&m.RuleID, &m.RuleName, &m.Product.ID, &m.Product.Name, &m.Product.Category,
+			&m.PriceID, &m.Price, &m.PreviousPrice, &m.ChangePercent)
+		if err != nil {
+			kp.log.Error("Failed to scan row", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		matches = append(matches, m)
+	}
+
+	if rows.Err() != nil {
+		kp.log.Error("Error occurred during rows iteration", zap.Error(rows.Err()))
+		return nil, fmt.Errorf("error during rows iteration: %w", rows.Err())
+	}
+	return matches, nil
+}
+
+func scanAlertRule(row pgx.Row) (*models.AlertRule, error) {
+	var rule models.AlertRule
+	err := row.Scan(&rule.ID, &rule.Name, &rule.ProductID, &rule.Category, &rule.Condition, &rule.Threshold, &rule.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan alert rule: %w", err)
+	}
+	return &rule, nil
+}
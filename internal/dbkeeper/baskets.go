@@ -0,0 +1,180 @@
+package dbkeeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"github.com/drstein77/priceanalyzer/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+// basketQuery selects baskets together with their items.
+const basketQuery = `
+	SELECT b.id, b.name,
+	       COALESCE(ARRAY_AGG(i.product_id ORDER BY i.product_id) FILTER (WHERE i.product_id IS NOT NULL), '{}'),
+	       COALESCE(ARRAY_AGG(i.weight::float8 ORDER BY i.product_id) FILTER (WHERE i.product_id IS NOT NULL), '{}')
+	FROM baskets b
+	LEFT JOIN basket_items i ON i.basket_id = b.id
+`
+
+// ListBaskets returns all baskets ordered by name.
+func (kp *DBKeeper) ListBaskets(ctx context.Context) ([]models.Basket, error) {
+	if kp.pool == nil {
+		return nil, fmt.Errorf("database connection pool is nil")
+	}
+
+	rows, err := kp.pool.Query(ctx, basketQuery+` GROUP BY b.id ORDER BY b.name`)
+	if err != nil {
+		kp.log.Error("Failed to execute query", zap.Error(err))
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	baskets := []models.Basket{}
+	for rows.Next() {
+		basket, err := scanBasket(rows)
+		if err != nil {
+			kp.log.Error("Failed to scan row", zap.Error(err))
+			return nil, err
+		}
+		baskets = append(baskets, *basket)
+	}
+
+	if rows.Err() != nil {
+		kp.log.Error("Error occurred during rows iteration", zap.Error(rows.Err()))
+		return nil, fmt.Errorf("error during rows iteration: %w", rows.Err())
+	}
+	return baskets, nil
+}
+
+// GetBasket returns the basket with the given name or storage.ErrNotFound.
+func (kp *DBKeeper) GetBasket(ctx context.Context, name string) (*models.Basket, error) {
+	if kp.pool == nil {
+		return nil, fmt.Errorf("database connection pool is nil")
+	}
+	return scanBasket(kp.pool.QueryRow(ctx, basketQuery+` WHERE b.name = $1 GROUP BY b.id`, name))
+}
+
+// CreateBasket stores a new basket with its items.
+func (kp *DBKeeper) CreateBasket(ctx context.Context, basket models.Basket) (*models.Basket, error) {
+	var created *models.Basket
+	err := kp.inTx(ctx, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `INSERT INTO baskets (name) VALUES ($1) RETURNING id`, basket.Name).Scan(&basket.ID)
+		if err != nil {
+			return basketError(err)
+		}
+		if err := saveBasketItems(ctx, tx, basket); err != nil {
+			return err
+		}
+
+		created, err = scanBasket(tx.QueryRow(ctx, basketQuery+` WHERE b.id = $1 GROUP BY b.id`, basket.ID))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// UpdateBasket replaces the name and items of the basket with the given name.
+func (kp *DBKeeper) UpdateBasket(ctx context.Context, name string, basket models.Basket) (*models.Basket, error) {
+	var updated *models.Basket
+	err := kp.inTx(ctx, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `
+			UPDATE baskets SET name = $2 WHERE name = $1 RETURNING id
+		`, name, basket.Name).Scan(&basket.ID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return storage.ErrNotFound
+		}
+		if err != nil {
+			return basketError(err)
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM basket_items WHERE basket_id = $1`, basket.ID); err != nil {
+			return fmt.Errorf("failed to clear basket items: %w", err)
+		}
+		if err := saveBasketItems(ctx, tx, basket); err != nil {
+			return err
+		}
+
+		updated, err = scanBasket(tx.QueryRow(ctx, basketQuery+` WHERE b.id = $1 GROUP BY b.id`, basket.ID))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeleteBasket removes the basket with the given name.
+func (kp *DBKeeper) DeleteBasket(ctx context.Context, name string) error {
+	if kp.pool == nil {
+		return fmt.Errorf("database connection pool is nil")
+	}
+
+	tag, err := kp.pool.Exec(ctx, `DELETE FROM baskets WHERE name = $1`, name)
+	if err != nil {
+		kp.log.Error("Failed to delete basket", zap.Error(err))
+		return fmt.Errorf("failed to delete basket: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+func saveBasketItems(ctx context.Context, tx pgx.Tx, basket models.Basket) error {
+	productIDs := make([]int, len(basket.Items))
+	weights := make([]float64, len(basket.Items))
+	for i, item := range basket.Items {
+		productIDs[i] = item.ProductID
+		weights[i] = item.Weight
+	}
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO basket_items (basket_id, product_id, weight)
+		SELECT $1, product_id, weight FROM unnest($2::int[], $3::numeric[]) AS items (product_id, weight)
+	`, basket.ID, productIDs, weights)
+	if err != nil {
+		return basketError(err)
+	}
+	return nil
+}
+
+// basketError turns a unique violation into storage.ErrConflict and a reference
+// to an unknown product into storage.ErrInvalidBasket.
+func basketError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505":
+			return storage.ErrConflict
+		case "23503":
+			return fmt.Errorf("%w: unknown product", storage.ErrInvalidBasket)
+		}
+	}
+	return fmt.Errorf("failed to save basket: %w", err)
+}
+
+func scanBasket(row pgx.Row) (*models.Basket, error) {
+	var basket models.Basket
+	var productIDs []int
+	var weights []float64
+	err := row.Scan(&basket.ID, &basket.Name, &productIDs, &weights)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan basket: %w", err)
+	}
+
+	basket.Items = make([]models.BasketItem, len(productIDs))
+	for i := range productIDs {
+		basket.Items[i] = models.BasketItem{ProductID: productIDs[i], Weight: weights[i]}
+	}
+	return &basket, nil
+}
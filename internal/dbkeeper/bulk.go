@@ -0,0 +1,203 @@
+package dbkeeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"github.com/drstein77/priceanalyzer/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+// DeleteProducts moves every product matching the filter to the trash and returns their number.
+func (kp *DBKeeper) DeleteProducts(ctx context.Context, filter models.ProductFilter) (int64, error) {
+	// Products already in the trash keep their original deletion time
+	filter.IncludeDeleted = false
+
+	var b whereBuilder
+	b.filter(filter)
+
+	var affected int64
+	err := kp.inTx(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, fmt.Sprintf(`UPDATE prices SET deleted_at = NOW() %s RETURNING product_id`, b.where()), b.args...)
+		if err != nil {
+			return fmt.Errorf("failed to delete products: %w", err)
+		}
+
+		touched := make(map[int]bool)
+		affected, err = collectProductIDs(rows, touched)
+		if err != nil {
+			return err
+		}
+		return refreshHistory(ctx, tx, idList(touched))
+	})
+	if err != nil {
+		kp.log.Error("Failed to delete products", zap.Error(err))
+		return 0, err
+	}
+
+	kp.log.Info("Products deleted", zap.Int64("count", affected))
+	return affected, nil
+}
+
+// UpdateProducts applies a change to every live product matching the filter and returns their number.
+// A price change is a percentage of the current price, rounded to cents. Renaming the
+// category moves the rows to the products with the new category, which is created in
+// the same transaction when createCategory is set and it does not exist yet.
+// A change that would make a row equal to another live row yields storage.ErrConflict,
+// and one that takes prices out of the storable range storage.ErrInvalidUpdate.
+func (kp *DBKeeper) UpdateProducts(ctx context.Context, filter models.ProductFilter, update models.BulkUpdate,
+	createCategory bool,
+) (int64, error) {
+	// Products in the trash are left as they were deleted
+	filter.IncludeDeleted = false
+
+	var affected int64
+	err := kp.inTx(ctx, func(tx pgx.Tx) error {
+		touched := make(map[int]bool)
+
+		if update.Category != nil && createCategory {
+			category, err := registerCategory(ctx, tx, *update.Category)
+			if err != nil {
+				return err
+			}
+			update.Category = &category
+		}
+		if err := checkBulkDuplicates(ctx, tx, filter, update); err != nil {
+			return err
+		}
+
+		if update.Category != nil {
+			// Remember the products the rows belong to before the rename and register the new ones
+			var b whereBuilder
+			b.filter(filter)
+			rows, err := tx.Query(ctx, fmt.Sprintf(`SELECT DISTINCT product_id FROM prices %s`, b.where()), b.args...)
+			if err != nil {
+				return fmt.Errorf("failed to select products: %w", err)
+			}
+			if _, err := collectProductIDs(rows, touched); err != nil {
+				return err
+			}
+
+			var r whereBuilder
+			category := r.arg(*update.Category)
+			r.filter(filter)
+			_, err = tx.Exec(ctx, fmt.Sprintf(`
+				INSERT INTO products (name, category)
+				SELECT DISTINCT name, %s::text FROM prices %s
+				ON CONFLICT DO NOTHING
+			`, category, r.where()), r.args...)
+			if err != nil {
+				return fmt.Errorf("failed to register products: %w", err)
+			}
+		}
+
+		var b whereBuilder
+		var set []string
+		if update.Category != nil {
+			category := b.arg(*update.Category)
+			set = append(set,
+				"category = "+category,
+				fmt.Sprintf("product_id = (SELECT id FROM products WHERE products.name = prices.name AND products.category = %s)", category),
+			)
+		}
+		if update.PriceChangePercent != nil {
+			set = append(set, "price = "+changedPrice(&b, *update.PriceChangePercent))
+		}
+		b.filter(filter)
+
+		sql := fmt.Sprintf(`UPDATE prices SET %s %s RETURNING product_id`, strings.Join(set, ", "), b.where())
+		rows, err := tx.Query(ctx, sql, b.args...)
+		if err != nil {
+			return fmt.Errorf("failed to update products: %w", err)
+		}
+
+		affected, err = collectProductIDs(rows, touched)
+		if err != nil {
+			return err
+		}
+		return refreshHistory(ctx, tx, idList(touched))
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "22003" {
+			return 0, fmt.Errorf("%w: the price change takes prices out of range", storage.ErrInvalidUpdate)
+		}
+		if !errors.Is(err, storage.ErrConflict) {
+			kp.log.Error("Failed to update products", zap.Error(err))
+		}
+		return 0, err
+	}
+
+	kp.log.Info("Products updated", zap.Int64("count", affected))
+	return affected, nil
+}
+
+// changedPrice returns the expression of a price changed by percent, rounded to cents.
+func changedPrice(b *whereBuilder, percent float64) string {
+	return fmt.Sprintf("ROUND(price * (1 + %s::numeric / 100), 2)", b.arg(percent))
+}
+
+// checkBulkDuplicates returns storage.ErrConflict if the update would give a row matching
+// the filter the same name, category, price and date as another live row, either one
+// left as it is or another updated row that differed before.
+func checkBulkDuplicates(ctx context.Context, tx pgx.Tx, filter models.ProductFilter, update models.BulkUpdate) error {
+	var b whereBuilder
+	category, price := "category", "price"
+	if update.Category != nil {
+		category = b.arg(*update.Category) + "::text"
+	}
+	if update.PriceChangePercent != nil {
+		price = changedPrice(&b, *update.PriceChangePercent)
+	}
+	b.filter(filter)
+
+	var exists bool
+	err := tx.QueryRow(ctx, fmt.Sprintf(`
+		WITH changed AS (
+			SELECT id, name, create_date, category AS old_category, price AS old_price,
+			       %s AS category, %s AS price
+			FROM prices
+			%s
+		)
+		SELECT EXISTS (
+			SELECT 1
+			FROM changed
+			JOIN prices p ON p.name = changed.name AND p.category = changed.category
+				AND p.price = changed.price AND p.create_date = changed.create_date
+			WHERE p.deleted_at IS NULL AND p.id NOT IN (SELECT id FROM changed)
+		) OR EXISTS (
+			SELECT 1
+			FROM changed
+			GROUP BY name, category, price, create_date
+			HAVING COUNT(DISTINCT (old_category, old_price)) > 1
+		)
+	`, category, price, b.where()), b.args...).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check for duplicates: %w", err)
+	}
+	if exists {
+		return storage.ErrConflict
+	}
+	return nil
+}
+
+// PurgeDeleted permanently removes products that were deleted longer than retention ago.
+// The cutoff is taken from the database clock, which also set the deletion times.
+func (kp *DBKeeper) PurgeDeleted(ctx context.Context, retention time.Duration) (int64, error) {
+	if kp.pool == nil {
+		return 0, fmt.Errorf("database connection pool is nil")
+	}
+
+	tag, err := kp.pool.Exec(ctx, `DELETE FROM prices WHERE deleted_at < NOW() - make_interval(secs => $1)`, retention.Seconds())
+	if err != nil {
+		kp.log.Error("Failed to purge deleted products", zap.Error(err))
+		return 0, fmt.Errorf("failed to purge deleted products: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
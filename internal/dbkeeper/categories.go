@@ -0,0 +1,456 @@
+package dbkeeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"github.com/drstein77/priceanalyzer/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+// categoryQuery selects categories together with their aliases.
+const categoryQuery = `
+	SELECT c.id, c.name, c.parent_id,
+	       COALESCE(ARRAY_AGG(a.alias ORDER BY a.alias) FILTER (WHERE a.alias IS NOT NULL), '{}')
+	FROM categories c
+	LEFT JOIN category_aliases a ON a.category_id = c.id
+`
+
+// ListCategories returns all categories ordered by name.
+func (kp *DBKeeper) ListCategories(ctx context.Context) ([]models.Category, error) {
+	if kp.pool == nil {
+		return nil, fmt.Errorf("database connection pool is nil")
+	}
+
+	rows, err := kp.pool.Query(ctx, categoryQuery+` GROUP BY c.id ORDER BY c.name`)
+	if err != nil {
+		kp.log.Error("Failed to execute query", zap.Error(err))
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	categories := []models.Category{}
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			kp.log.Error("Failed to scan row", zap.Error(err))
+			return nil, err
+		}
+		categories = append(categories, *category)
+	}
+
+	if rows.Err() != nil {
+		kp.log.Error("Error occurred during rows iteration", zap.Error(rows.Err()))
+		return nil, fmt.Errorf("error during rows iteration: %w", rows.Err())
+	}
+	return categories, nil
+}
+
+// GetCategory returns the category with the given id or storage.ErrNotFound.
+func (kp *DBKeeper) GetCategory(ctx context.Context, id int) (*models.Category, error) {
+	if kp.pool == nil {
+		return nil, fmt.Errorf("database connection pool is nil")
+	}
+	return scanCategory(kp.pool.QueryRow(ctx, categoryQuery+` WHERE c.id = $1 GROUP BY c.id`, id))
+}
+
+// CreateCategory stores a new category with its aliases and moves prices filed
+// under any of its spellings to it.
+func (kp *DBKeeper) CreateCategory(ctx context.Context, category models.Category) (*models.Category, error) {
+	var created *models.Category
+	err := kp.inTx(ctx, func(tx pgx.Tx) error {
+		if err := checkParent(ctx, tx, category); err != nil {
+			return err
+		}
+
+		err := tx.QueryRow(ctx, `
+			INSERT INTO categories (name, parent_id) VALUES ($1, $2) RETURNING id
+		`, category.Name, category.ParentID).Scan(&category.ID)
+		if err != nil {
+			return categoryError(err)
+		}
+
+		if err := saveAliases(ctx, tx, category); err != nil {
+			return err
+		}
+		if err := recategorize(ctx, tx, spellings(category), category.Name); err != nil {
+			return err
+		}
+
+		created, err = scanCategory(tx.QueryRow(ctx, categoryQuery+` WHERE c.id = $1 GROUP BY c.id`, category.ID))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// UpdateCategory replaces the name, parent and aliases of a category.
+// Prices filed under its old name or any of its spellings are moved to the new name.
+func (kp *DBKeeper) UpdateCategory(ctx context.Context, category models.Category) (*models.Category, error) {
+	var updated *models.Category
+	err := kp.inTx(ctx, func(tx pgx.Tx) error {
+		var oldName string
+		err := tx.QueryRow(ctx, `SELECT name FROM categories WHERE id = $1 FOR UPDATE`, category.ID).Scan(&oldName)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return storage.ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get category: %w", err)
+		}
+
+		if err := checkParent(ctx, tx, category); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE categories SET name = $2, parent_id = $3 WHERE id = $1
+		`, category.ID, category.Name, category.ParentID)
+		if err != nil {
+			return categoryError(err)
+		}
+
+		if err := saveAliases(ctx, tx, category); err != nil {
+			return err
+		}
+		names := append(spellings(category), strings.ToLower(oldName))
+		if err := recategorize(ctx, tx, names, category.Name); err != nil {
+			return err
+		}
+
+		updated, err = scanCategory(tx.QueryRow(ctx, categoryQuery+` WHERE c.id = $1 GROUP BY c.id`, category.ID))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeleteCategory removes a category that has no subcategories and no prices filed under it.
+func (kp *DBKeeper) DeleteCategory(ctx context.Context, id int) error {
+	return kp.inTx(ctx, func(tx pgx.Tx) error {
+		var inUse bool
+		err := tx.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM categories WHERE parent_id = $1)
+			    OR EXISTS (SELECT 1 FROM prices WHERE category = (SELECT name FROM categories WHERE id = $1))
+		`, id).Scan(&inUse)
+		if err != nil {
+			return fmt.Errorf("failed to check category usage: %w", err)
+		}
+		if inUse {
+			return storage.ErrConflict
+		}
+
+		tag, err := tx.Exec(ctx, `DELETE FROM categories WHERE id = $1`, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete category: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return storage.ErrNotFound
+		}
+		return nil
+	})
+}
+
+// ResolveCategories maps free-text category names to canonical names, matching
+// names case-insensitively and by alias. Names that match nothing are left out of
+// the result; writes register them as new categories in their own transaction.
+func (kp *DBKeeper) ResolveCategories(ctx context.Context, names []string) (map[string]string, error) {
+	var resolved map[string]string
+	err := kp.inTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		resolved, err = resolveCategories(ctx, tx, names, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resolved, nil
+}
+
+// resolveCategories resolves category names within tx, as described for ResolveCategories.
+// When create is set, names that match nothing are registered as new top-level categories.
+func resolveCategories(ctx context.Context, tx pgx.Tx, names []string, create bool) (map[string]string, error) {
+	resolved := make(map[string]string, len(names))
+	if err := lookupCategories(ctx, tx, names, resolved); err != nil {
+		return nil, err
+	}
+	if !create {
+		return resolved, nil
+	}
+
+	var unknown []string
+	for _, name := range names {
+		if _, ok := resolved[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return resolved, nil
+	}
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO categories (name)
+		SELECT * FROM unnest($1::text[])
+		ON CONFLICT DO NOTHING
+	`, unknown)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create categories: %w", err)
+	}
+	if err := lookupCategories(ctx, tx, unknown, resolved); err != nil {
+		return nil, err
+	}
+	return resolved, nil
+}
+
+// registerCategories creates the categories of the products that do not exist
+// yet within tx and replaces the categories with their canonical names.
+func registerCategories(ctx context.Context, tx pgx.Tx, products []models.Product) error {
+	seen := make(map[string]bool)
+	var names []string
+	for _, product := range products {
+		if name := product.Category; name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	resolved, err := resolveCategories(ctx, tx, names, true)
+	if err != nil {
+		return err
+	}
+	for i, product := range products {
+		if canonical, ok := resolved[product.Category]; ok {
+			products[i].Category = canonical
+		}
+	}
+	return nil
+}
+
+// registerCategory creates a single category within tx when it does not exist yet
+// and returns its canonical name.
+func registerCategory(ctx context.Context, tx pgx.Tx, name string) (string, error) {
+	products := []models.Product{{Category: name}}
+	if err := registerCategories(ctx, tx, products); err != nil {
+		return "", err
+	}
+	return products[0].Category, nil
+}
+
+func lookupCategories(ctx context.Context, tx pgx.Tx, names []string, into map[string]string) error {
+	rows, err := tx.Query(ctx, `
+		SELECT keys.raw, c.name
+		FROM unnest($1::text[]) AS keys (raw)
+		JOIN categories c ON LOWER(c.name) = LOWER(keys.raw)
+			OR c.id = (SELECT category_id FROM category_aliases WHERE alias = LOWER(keys.raw))
+	`, names)
+	if err != nil {
+		return fmt.Errorf("failed to resolve categories: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var raw, name string
+		if err := rows.Scan(&raw, &name); err != nil {
+			return fmt.Errorf("failed to scan category: %w", err)
+		}
+		into[raw] = name
+	}
+	if rows.Err() != nil {
+		return fmt.Errorf("error during rows iteration: %w", rows.Err())
+	}
+	return nil
+}
+
+// checkParent makes sure the parent of a category exists and is not the category itself or one of its descendants.
+func checkParent(ctx context.Context, tx pgx.Tx, category models.Category) error {
+	if category.ParentID == nil {
+		return nil
+	}
+
+	var exists, cycle bool
+	err := tx.QueryRow(ctx, `
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM categories WHERE id = $1
+			UNION ALL
+			SELECT c.id FROM categories c JOIN subtree ON c.parent_id = subtree.id
+		)
+		SELECT EXISTS (SELECT 1 FROM categories WHERE id = $2),
+		       EXISTS (SELECT 1 FROM subtree WHERE id = $2)
+	`, category.ID, *category.ParentID).Scan(&exists, &cycle)
+	if err != nil {
+		return fmt.Errorf("failed to check parent category: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("%w: parent category %d does not exist", storage.ErrInvalidCategory, *category.ParentID)
+	}
+	if cycle {
+		return fmt.Errorf("%w: a category cannot be nested under itself", storage.ErrInvalidCategory)
+	}
+	return nil
+}
+
+// saveAliases replaces the aliases of a category. An alias may not name or alias another category.
+func saveAliases(ctx context.Context, tx pgx.Tx, category models.Category) error {
+	var taken bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM categories WHERE LOWER(name) = ANY($2) AND id <> $1)
+		    OR EXISTS (SELECT 1 FROM category_aliases WHERE alias = LOWER($3) AND category_id <> $1)
+	`, category.ID, category.Aliases, category.Name).Scan(&taken)
+	if err != nil {
+		return fmt.Errorf("failed to check aliases: %w", err)
+	}
+	if taken {
+		return storage.ErrConflict
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM category_aliases WHERE category_id = $1`, category.ID); err != nil {
+		return fmt.Errorf("failed to clear aliases: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO category_aliases (alias, category_id)
+		SELECT alias, $1 FROM unnest($2::text[]) AS alias
+	`, category.ID, category.Aliases)
+	if err != nil {
+		return categoryError(err)
+	}
+	return nil
+}
+
+// recategorize files prices recorded under any of the lower-case spellings under the
+// canonical name, relinking them to products and refreshing the affected history.
+// Products keep their identity where they can: a product is renamed in place unless
+// a product with the canonical category already exists, in which case its basket
+// items and alert rules move to that product before it is removed.
+func recategorize(ctx context.Context, tx pgx.Tx, names []string, canonical string) error {
+	touched := make(map[int]bool)
+
+	rows, err := tx.Query(ctx, `
+		SELECT DISTINCT product_id FROM prices
+		WHERE LOWER(TRIM(category)) = ANY($1) AND category <> $2
+	`, names, canonical)
+	if err != nil {
+		return fmt.Errorf("failed to select products: %w", err)
+	}
+	if _, err := collectProductIDs(rows, touched); err != nil {
+		return err
+	}
+
+	// Rename one product per name that has no counterpart in the canonical category yet
+	_, err = tx.Exec(ctx, `
+		UPDATE products SET category = $2
+		WHERE id IN (
+			SELECT DISTINCT ON (old.name) old.id
+			FROM products old
+			WHERE LOWER(TRIM(old.category)) = ANY($1) AND old.category <> $2
+			  AND NOT EXISTS (SELECT 1 FROM products target WHERE target.name = old.name AND target.category = $2)
+			ORDER BY old.name, old.id
+		)
+	`, names, canonical)
+	if err != nil {
+		return fmt.Errorf("failed to rename products: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO products (name, category)
+		SELECT DISTINCT name, $2::text FROM prices
+		WHERE LOWER(TRIM(category)) = ANY($1) AND category <> $2
+		ON CONFLICT DO NOTHING
+	`, names, canonical)
+	if err != nil {
+		return fmt.Errorf("failed to register products: %w", err)
+	}
+
+	// The remaining products merge into their counterparts
+	_, err = tx.Exec(ctx, `
+		INSERT INTO basket_items (basket_id, product_id, weight)
+		SELECT items.basket_id, target.id, items.weight
+		FROM basket_items items
+		JOIN products old ON old.id = items.product_id
+		JOIN products target ON target.name = old.name AND target.category = $2
+		WHERE LOWER(TRIM(old.category)) = ANY($1) AND old.category <> $2
+		ON CONFLICT DO NOTHING
+	`, names, canonical)
+	if err != nil {
+		return fmt.Errorf("failed to move basket items: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE alert_rules SET product_id = target.id
+		FROM products old
+		JOIN products target ON target.name = old.name AND target.category = $2
+		WHERE alert_rules.product_id = old.id AND LOWER(TRIM(old.category)) = ANY($1) AND old.category <> $2
+	`, names, canonical)
+	if err != nil {
+		return fmt.Errorf("failed to move alert rules: %w", err)
+	}
+
+	rows, err = tx.Query(ctx, `
+		UPDATE prices
+		SET category = $2,
+		    product_id = (SELECT id FROM products WHERE products.name = prices.name AND products.category = $2)
+		WHERE LOWER(TRIM(category)) = ANY($1) AND category <> $2
+		RETURNING product_id
+	`, names, canonical)
+	if err != nil {
+		return fmt.Errorf("failed to recategorize prices: %w", err)
+	}
+	if _, err := collectProductIDs(rows, touched); err != nil {
+		return err
+	}
+
+	// Merged products have no prices left; their history and basket items go with them
+	_, err = tx.Exec(ctx, `
+		DELETE FROM products
+		WHERE LOWER(TRIM(category)) = ANY($1) AND category <> $2
+	`, names, canonical)
+	if err != nil {
+		return fmt.Errorf("failed to remove merged products: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE alert_rules SET category = $2
+		WHERE LOWER(TRIM(category)) = ANY($1) AND category <> $2
+	`, names, canonical)
+	if err != nil {
+		return fmt.Errorf("failed to recategorize alert rules: %w", err)
+	}
+
+	return refreshHistory(ctx, tx, idList(touched))
+}
+
+// spellings returns the lower-case name and aliases of a category.
+func spellings(category models.Category) []string {
+	names := []string{strings.ToLower(category.Name)}
+	return append(names, category.Aliases...)
+}
+
+// categoryError turns a unique violation into storage.ErrConflict.
+func categoryError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return storage.ErrConflict
+	}
+	return fmt.Errorf("failed to save category: %w", err)
+}
+
+func scanCategory(row pgx.Row) (*models.Category, error) {
+	var category models.Category
+	err := row.Scan(&category.ID, &category.Name, &category.ParentID, &category.Aliases)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan category: %w", err)
+	}
+	return &category, nil
+}
@@ -0,0 +1,85 @@
+package dbkeeper
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"go.uber.org/zap"
+)
+
+// ComparePeriods summarizes the products matching the filter per category in two periods.
+func (kp *DBKeeper) ComparePeriods(ctx context.Context, query models.CompareQuery) ([]models.CategoryComparison, error) {
+	if kp.pool == nil {
+		return nil, fmt.Errorf("database connection pool is nil")
+	}
+
+	filter := query.Filter
+
+	var b whereBuilder
+	b.filter(filter)
+	inA := fmt.Sprintf("create_date BETWEEN %s AND %s", b.arg(query.A.Start), b.arg(query.A.End))
+	inB := fmt.Sprintf("create_date BETWEEN %s AND %s", b.arg(query.B.Start), b.arg(query.B.End))
+	b.add(fmt.Sprintf("(%s OR %s)", inA, inB))
+
+	sql := fmt.Sprintf(`
+		SELECT COALESCE(category, '') AS category,
+		       COUNT(*) FILTER (WHERE %[1]s),
+		       COALESCE(SUM(price) FILTER (WHERE %[1]s), 0)::float8,
+		       ROUND(AVG(price) FILTER (WHERE %[1]s), 2)::float8,
+		       COUNT(*) FILTER (WHERE %[2]s),
+		       COALESCE(SUM(price) FILTER (WHERE %[2]s), 0)::float8,
+		       ROUND(AVG(price) FILTER (WHERE %[2]s), 2)::float8
+		FROM %[3]s
+		%[4]s
+		GROUP BY 1
+		ORDER BY 1
+	`, inA, inB, b.source(filter), b.where())
+
+	rows, err := kp.pool.Query(ctx, sql, b.args...)
+	if err != nil {
+		kp.log.Error("Failed to execute query", zap.Error(err))
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	comparisons := []models.CategoryComparison{}
+	for rows.Next() {
+		var c models.CategoryComparison
+		err := rows.Scan(&c.Category, &c.A.Count, &c.A.Total, &c.A.Average, &c.B.Count, &c.B.Total, &c.B.Average)
+		if err != nil {
+			kp.log.Error("Failed to scan row", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		c.CountChange = c.B.Count - c.A.Count
+		c.TotalChange = roundCents(c.B.Total - c.A.Total)
+		c.TotalChangePercent = percentChange(c.A.Total, c.B.Total)
+		if c.A.Average != nil && c.B.Average != nil {
+			change := roundCents(*c.B.Average - *c.A.Average)
+			c.AverageChange = &change
+			c.AverageChangePercent = percentChange(*c.A.Average, *c.B.Average)
+		}
+		comparisons = append(comparisons, c)
+	}
+
+	if rows.Err() != nil {
+		kp.log.Error("Error occurred during rows iteration", zap.Error(rows.Err()))
+		return nil, fmt.Errorf("error during rows iteration: %w", rows.Err())
+	}
+	return comparisons, nil
+}
+
+// percentChange returns the change from a to b in percent, or nil when a is zero.
+func percentChange(a, b float64) *float64 {
+	if a == 0 {
+		return nil
+	}
+	change := roundCents((b - a) / a * 100)
+	return &change
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -2,8 +2,8 @@ package dbkeeper
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/drstein77/priceanalyzer/internal/models"
@@ -49,7 +49,10 @@ func NewDBKeeper(ctx context.Context, dsn func() string, log Log) *DBKeeper {
 	}
 }
 
-func (kp *DBKeeper) InsertProducts(ctx context.Context, products []models.Product) (*models.ProcessResponse, error) {
+// InsertProducts stores the products of an upload and returns the totals along with
+// the upload stats. With createCategories set, categories the products name that do
+// not exist yet are created in the same transaction.
+func (kp *DBKeeper) InsertProducts(ctx context.Context, products []models.Product, createCategories bool) (*models.ProcessResponse, error) {
 	if len(products) == 0 {
 		return &models.ProcessResponse{}, nil
 	}
@@ -71,41 +74,73 @@ func (kp *DBKeeper) InsertProducts(ctx context.Context, products []models.Produc
 		}
 	}()
 
-	stmt := `INSERT INTO prices (name, category, price, create_date) VALUES ($1, $2, $3, $4)`
-	batch := &pgx.Batch{}
-	for _, product := range products {
-		batch.Queue(stmt, product.Name, product.Category, product.Price, product.CreatedAt)
+	if createCategories {
+		if categoryErr := registerCategories(ctx, tx, products); categoryErr != nil {
+			err = categoryErr
+			return nil, err
+		}
+	}
+
+	// Link every row to its product, registering new products on the way
+	keys := make([]productKey, len(products))
+	for i, product := range products {
+		keys[i] = productKey{name: product.Name, category: product.Category}
+	}
+	idsByKey, resolveErr := resolveProducts(ctx, tx, keys)
+	if resolveErr != nil {
+		err = resolveErr
+		return nil, err
 	}
 
-	br := tx.SendBatch(ctx, batch)
+	// Record the upload so its rows can be told apart later
+	var uploadID int
+	if uploadErr := tx.QueryRow(ctx, `INSERT INTO uploads DEFAULT VALUES RETURNING id`).Scan(&uploadID); uploadErr != nil {
+		err = fmt.Errorf("failed to record upload: %w", uploadErr)
+		return nil, err
+	}
 
-	for range products {
-		if _, execErr := br.Exec(); execErr != nil {
-			err = fmt.Errorf("failed to execute batch query: %w", execErr)
-			return nil, err
-		}
+	// Insert all rows in one statement so the aggregate triggers fire once per upload
+	productIDs := make([]int, len(products))
+	names := make([]string, len(products))
+	categories := make([]string, len(products))
+	prices := make([]float64, len(products))
+	dates := make([]time.Time, len(products))
+	touched := make(map[int]bool)
+	for i, product := range products {
+		productIDs[i] = idsByKey[keys[i]]
+		names[i] = product.Name
+		categories[i] = product.Category
+		prices[i] = product.Price
+		dates[i] = product.CreatedAt
+		touched[productIDs[i]] = true
+	}
+
+	_, insertErr := tx.Exec(ctx, `
+		INSERT INTO prices (upload_id, product_id, name, category, price, create_date)
+		SELECT $1, product_id, name, category, price, create_date
+		FROM unnest($2::int[], $3::text[], $4::text[], $5::numeric[], $6::timestamp[])
+			AS uploaded (product_id, name, category, price, create_date)
+	`, uploadID, productIDs, names, categories, prices, dates)
+	if insertErr != nil {
+		err = fmt.Errorf("failed to insert prices: %w", insertErr)
+		return nil, err
 	}
 
-	if closeErr := br.Close(); closeErr != nil {
-		kp.log.Error("Failed to close batch", zap.Error(closeErr))
+	if historyErr := refreshHistory(ctx, tx, idList(touched)); historyErr != nil {
+		err = historyErr
+		return nil, err
 	}
 
-	var resp models.ProcessResponse
 	statsCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	row := tx.QueryRow(statsCtx, `
-		SELECT COUNT(*), COUNT(DISTINCT category), COALESCE(SUM(price), 0)
-		FROM prices
-	`)
-
-	if scanErr := row.Scan(&resp.TotalItems, &resp.TotalCategories, &resp.TotalPrice); scanErr != nil {
-		if errors.Is(scanErr, pgx.ErrNoRows) {
-			return &models.ProcessResponse{}, nil
-		}
-		err = fmt.Errorf("failed to calculate stats: %w", scanErr)
+	resp, statsErr := queryStats(statsCtx, tx, models.ProductFilter{})
+	if statsErr != nil {
+		err = statsErr
 		return nil, err
 	}
+	resp.UploadID = uploadID
+	resp.Upload = uploadStats(uploadID, products)
 
 	kp.log.Info("Committing transaction...")
 	if commitErr := tx.Commit(ctx); commitErr != nil {
@@ -114,7 +149,26 @@ func (kp *DBKeeper) InsertProducts(ctx context.Context, products []models.Produc
 	}
 
 	kp.log.Info("Products successfully inserted, stats calculated.")
-	return &resp, nil
+	return resp, nil
+}
+
+// uploadStats summarizes the rows inserted by an upload.
+func uploadStats(id int, products []models.Product) *models.UploadStats {
+	stats := &models.UploadStats{ID: id, RowsInserted: len(products)}
+	categories := make(map[string]bool)
+	for i, product := range products {
+		categories[product.Category] = true
+		stats.TotalPrice += product.Price
+		if stats.FirstDate == nil || product.CreatedAt.Before(*stats.FirstDate) {
+			stats.FirstDate = &products[i].CreatedAt
+		}
+		if stats.LastDate == nil || product.CreatedAt.After(*stats.LastDate) {
+			stats.LastDate = &products[i].CreatedAt
+		}
+	}
+	stats.CategoriesTouched = len(categories)
+	stats.TotalPrice = math.Round(stats.TotalPrice*100) / 100
+	return stats
 }
 
 func (kp *DBKeeper) GetAllProducts(ctx context.Context) ([]models.Product, error) {
@@ -123,10 +177,11 @@ func (kp *DBKeeper) GetAllProducts(ctx context.Context) ([]models.Product, error
 		return nil, fmt.Errorf("database connection pool is nil")
 	}
 
-	// SQL query to fetch all data from the table
+	// SQL query to fetch all products that are not deleted
 	query := `
 		SELECT id, name, category, price, create_date
 		FROM prices
+		WHERE deleted_at IS NULL
 	`
 
 	// Executing the query
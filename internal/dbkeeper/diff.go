@@ -0,0 +1,96 @@
+package dbkeeper
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"go.uber.org/zap"
+)
+
+// diffOrders maps the sort keys of a diff to their SQL expressions over the prev and curr snapshots.
+var diffOrders = map[string]string{
+	"magnitude": "ABS(curr.price - prev.price)",
+	"change":    "curr.price - prev.price",
+	"percent":   "(curr.price - prev.price) / NULLIF(prev.price, 0)",
+	"name":      "p.name",
+}
+
+// DiffPrices compares two snapshots of prices product by product and returns the
+// products that were added, removed or repriced between them. Changes without a
+// value for the sort key, such as added and removed products, come last.
+func (kp *DBKeeper) DiffPrices(ctx context.Context, query models.DiffQuery) ([]models.PriceChange, error) {
+	if kp.pool == nil {
+		return nil, fmt.Errorf("database connection pool is nil")
+	}
+
+	order, ok := diffOrders[query.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unsupported sort key %q", query.Sort)
+	}
+	direction := "ASC"
+	if query.Desc {
+		direction = "DESC"
+	}
+
+	var b whereBuilder
+	sql := fmt.Sprintf(`
+		SELECT p.id, p.name, p.category,
+		       prev.price::float8, curr.price::float8,
+		       (curr.price - prev.price)::float8,
+		       ROUND((curr.price - prev.price) / NULLIF(prev.price, 0) * 100, 2)::float8
+		FROM (%s) prev
+		FULL JOIN (%s) curr ON curr.product_id = prev.product_id
+		JOIN products p ON p.id = COALESCE(curr.product_id, prev.product_id)
+		WHERE prev.price IS DISTINCT FROM curr.price
+		ORDER BY %s %s NULLS LAST, p.id
+	`, b.snapshot(query.From), b.snapshot(query.To), order, direction)
+
+	rows, err := kp.pool.Query(ctx, sql, b.args...)
+	if err != nil {
+		kp.log.Error("Failed to execute query", zap.Error(err))
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []models.PriceChange{}
+	for rows.Next() {
+		var c models.PriceChange
+		err := rows.Scan(&c.Product.ID, &c.Product.Name, &c.Product.Category,
+			&c.OldPrice, &c.NewPrice, &c.Change, &c.ChangePercent)
+		if err != nil {
+			kp.log.Error("Failed to scan row", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		switch {
+		case c.OldPrice == nil:
+			c.Status = models.ChangeAdded
+		case c.NewPrice == nil:
+			c.Status = models.ChangeRemoved
+		default:
+			c.Status = models.ChangeRepriced
+		}
+		changes = append(changes, c)
+	}
+
+	if rows.Err() != nil {
+		kp.log.Error("Error occurred during rows iteration", zap.Error(rows.Err()))
+		return nil, fmt.Errorf("error during rows iteration: %w", rows.Err())
+	}
+	return changes, nil
+}
+
+// snapshot returns a query for the price of every product in a diff side: the
+// latest live row of each product in an upload, or the row effective as of a moment.
+func (b *whereBuilder) snapshot(side models.DiffSide) string {
+	if side.UploadID != nil {
+		return fmt.Sprintf(`
+			SELECT DISTINCT ON (product_id) product_id, price
+			FROM prices
+			WHERE upload_id = %s AND deleted_at IS NULL
+			ORDER BY product_id, create_date DESC, id DESC
+		`, b.arg(*side.UploadID))
+	}
+	return "SELECT product_id, price FROM " + b.source(models.ProductFilter{AsOf: side.AsOf})
+}
@@ -0,0 +1,57 @@
+package dbkeeper
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"go.uber.org/zap"
+)
+
+// StreamProducts passes every product matching the filter to fn in id order.
+// Rows are decoded one at a time as they arrive from the database, so memory
+// use does not depend on the size of the result. Iteration stops at the first
+// error returned by fn.
+func (kp *DBKeeper) StreamProducts(ctx context.Context, filter models.ProductFilter, fn func(models.Product) error) error {
+	if kp.pool == nil {
+		return fmt.Errorf("database connection pool is nil")
+	}
+
+	var b whereBuilder
+	b.filter(filter)
+
+	sql := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		%s
+		ORDER BY id
+	`, productColumns, b.source(filter), b.where())
+
+	rows, err := kp.pool.Query(ctx, sql, b.args...)
+	if err != nil {
+		kp.log.Error("Failed to execute query", zap.Error(err))
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			kp.log.Error("Failed to scan row", zap.Error(err))
+			return err
+		}
+		if err := fn(*product); err != nil {
+			return err
+		}
+		count++
+	}
+
+	if rows.Err() != nil {
+		kp.log.Error("Error occurred during rows iteration", zap.Error(rows.Err()))
+		return fmt.Errorf("error during rows iteration: %w", rows.Err())
+	}
+
+	kp.log.Info("Successfully streamed products", zap.Int("count", count))
+	return nil
+}
@@ -0,0 +1,93 @@
+package dbkeeper
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+)
+
+// whereBuilder collects SQL conditions together with their positional arguments.
+type whereBuilder struct {
+	conds []string
+	args  []any
+}
+
+// add appends a condition; every "?" in it is replaced with the next placeholder.
+func (b *whereBuilder) add(cond string, args ...any) {
+	for _, arg := range args {
+		b.args = append(b.args, arg)
+		cond = strings.Replace(cond, "?", fmt.Sprintf("$%d", len(b.args)), 1)
+	}
+	b.conds = append(b.conds, cond)
+}
+
+// arg registers an argument and returns its placeholder.
+func (b *whereBuilder) arg(value any) string {
+	b.args = append(b.args, value)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+// source returns the relation to select products from under the name prices.
+// With an as-of time it is a snapshot holding, for every product, the latest
+// row dated no later than that time which had not been deleted by then; this is
+// the row the price history reports as effective at that moment.
+func (b *whereBuilder) source(f models.ProductFilter) string {
+	if f.AsOf == nil {
+		return "prices"
+	}
+
+	asOf := b.arg(*f.AsOf)
+	return fmt.Sprintf(`(
+		SELECT DISTINCT ON (product_id) *
+		FROM prices
+		WHERE create_date <= %[1]s AND (deleted_at IS NULL OR deleted_at > %[1]s)
+		ORDER BY product_id, create_date DESC, id DESC
+	) prices`, asOf)
+}
+
+// filter adds the conditions of a product filter.
+// Deleted products are excluded unless the filter asks for them; a snapshot
+// taken with source already accounts for deletions.
+func (b *whereBuilder) filter(f models.ProductFilter) {
+	if !f.IncludeDeleted && f.AsOf == nil {
+		b.add("deleted_at IS NULL")
+	}
+	if f.Start != nil {
+		b.add("create_date >= ?", *f.Start)
+	}
+	if f.End != nil {
+		b.add("create_date <= ?", *f.End)
+	}
+	if f.Min != nil {
+		b.add("price >= ?", *f.Min)
+	}
+	if f.Max != nil {
+		b.add("price <= ?", *f.Max)
+	}
+	if f.ProductID != nil {
+		b.add("product_id = ?", *f.ProductID)
+	}
+	if f.Category != "" {
+		// A managed category also covers its aliases and subcategories
+		category := b.arg(f.Category)
+		b.add(fmt.Sprintf(`(category = %[1]s OR category IN (
+			WITH RECURSIVE tree AS (
+				SELECT id, name FROM categories
+				WHERE LOWER(name) = LOWER(%[1]s)
+				   OR id = (SELECT category_id FROM category_aliases WHERE alias = LOWER(%[1]s))
+				UNION ALL
+				SELECT c.id, c.name FROM categories c JOIN tree ON c.parent_id = tree.id
+			)
+			SELECT name FROM tree
+		))`, category))
+	}
+}
+
+// where returns the WHERE clause, or an empty string when there are no conditions.
+func (b *whereBuilder) where() string {
+	if len(b.conds) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(b.conds, " AND ")
+}
@@ -0,0 +1,117 @@
+package dbkeeper
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// GetHistogram counts the prices of the products matching the filter per bucket with WIDTH_BUCKET.
+// Without explicit edges the buckets span the lowest to the highest price, which is
+// counted in the last bucket; on a log scale only positive prices are spanned and
+// the others count as underflow.
+func (kp *DBKeeper) GetHistogram(ctx context.Context, query models.HistogramQuery) (*models.Histogram, error) {
+	histogram := &models.Histogram{Buckets: []models.HistogramBucket{}}
+
+	// The price range and the counts come from one snapshot so that every counted
+	// price falls inside the spanned range
+	var counts []int64
+	edges := query.Edges
+	spanned := len(edges) == 0
+	err := kp.inSnapshot(ctx, func(tx pgx.Tx) error {
+		if spanned {
+			var b whereBuilder
+			b.filter(query.Filter)
+			if query.Log {
+				b.add("price > 0")
+			}
+
+			var low, high *float64
+			sql := fmt.Sprintf(`SELECT MIN(price)::float8, MAX(price)::float8 FROM %s %s`, b.source(query.Filter), b.where())
+			if err := tx.QueryRow(ctx, sql, b.args...).Scan(&low, &high); err != nil {
+				return fmt.Errorf("failed to determine price range: %w", err)
+			}
+			if low == nil {
+				return nil
+			}
+			edges = spanEdges(*low, *high, query.Buckets, query.Log)
+		}
+
+		var b whereBuilder
+		b.filter(query.Filter)
+		sql := fmt.Sprintf(`
+			SELECT WIDTH_BUCKET(price::float8, %s::float8[]) AS bucket, COUNT(*)
+			FROM %s
+			%s
+			GROUP BY 1
+		`, b.arg(edges), b.source(query.Filter), b.where())
+
+		rows, err := tx.Query(ctx, sql, b.args...)
+		if err != nil {
+			return fmt.Errorf("failed to execute query: %w", err)
+		}
+		defer rows.Close()
+
+		counts = make([]int64, len(edges)+1)
+		for rows.Next() {
+			var bucket int
+			var count int64
+			if err := rows.Scan(&bucket, &count); err != nil {
+				return fmt.Errorf("failed to scan row: %w", err)
+			}
+			counts[bucket] = count
+		}
+		if rows.Err() != nil {
+			return fmt.Errorf("error during rows iteration: %w", rows.Err())
+		}
+		return nil
+	})
+	if err != nil {
+		kp.log.Error("Failed to build histogram", zap.Error(err))
+		return nil, err
+	}
+	if counts == nil {
+		return histogram, nil
+	}
+
+	// WIDTH_BUCKET puts values below the first edge in bucket 0 and values
+	// from the last edge on in bucket len(edges)
+	last := len(edges)
+	if spanned {
+		counts[last-1] += counts[last]
+		counts[last] = 0
+	}
+	histogram.Underflow = counts[0]
+	histogram.Overflow = counts[last]
+	for i := 1; i < last; i++ {
+		histogram.Buckets = append(histogram.Buckets, models.HistogramBucket{
+			Lower: edges[i-1],
+			Upper: edges[i],
+			Count: counts[i],
+		})
+	}
+	return histogram, nil
+}
+
+// spanEdges returns the edges of n equal-width buckets from low to high,
+// equal in log space when logScale is set.
+func spanEdges(low, high float64, n int, logScale bool) []float64 {
+	if low == high {
+		return []float64{low, high}
+	}
+
+	edges := make([]float64, n+1)
+	for i := range edges {
+		if logScale {
+			edges[i] = math.Exp(math.Log(low) + float64(i)*(math.Log(high)-math.Log(low))/float64(n))
+		} else {
+			edges[i] = low + float64(i)*(high-low)/float64(n)
+		}
+	}
+	edges[0], edges[n] = low, high
+	return edges
+}
@@ -0,0 +1,188 @@
+package dbkeeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"github.com/drstein77/priceanalyzer/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// productKey identifies a product by its name and category.
+type productKey struct {
+	name     string
+	category string
+}
+
+// resolveProducts returns the ids of the products with the given names and
+// categories, registering the ones seen for the first time.
+func resolveProducts(ctx context.Context, tx pgx.Tx, keys []productKey) (map[productKey]int, error) {
+	names := make([]string, 0, len(keys))
+	categories := make([]string, 0, len(keys))
+	seen := make(map[productKey]bool, len(keys))
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		names = append(names, key.name)
+		categories = append(categories, key.category)
+	}
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO products (name, category)
+		SELECT * FROM unnest($1::text[], $2::text[])
+		ORDER BY 1, 2
+		ON CONFLICT DO NOTHING
+	`, names, categories)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register products: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT products.id, products.name, products.category
+		FROM products
+		JOIN unnest($1::text[], $2::text[]) AS keys (name, category)
+			ON products.name = keys.name AND products.category = keys.category
+	`, names, categories)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve products: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make(map[productKey]int, len(names))
+	for rows.Next() {
+		var id int
+		var key productKey
+		if err := rows.Scan(&id, &key.name, &key.category); err != nil {
+			return nil, fmt.Errorf("failed to scan product: %w", err)
+		}
+		ids[key] = id
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("error during rows iteration: %w", rows.Err())
+	}
+	return ids, nil
+}
+
+// resolveProduct returns the id of a single product, registering it if needed.
+func resolveProduct(ctx context.Context, tx pgx.Tx, product models.Product) (int, error) {
+	key := productKey{name: product.Name, category: product.Category}
+	ids, err := resolveProducts(ctx, tx, []productKey{key})
+	if err != nil {
+		return 0, err
+	}
+	return ids[key], nil
+}
+
+// refreshHistory rebuilds the price history of the given products from their live prices.
+// A new period starts whenever the price differs from the previous day's; when a
+// product has several prices on the same date, the latest one is used.
+func refreshHistory(ctx context.Context, tx pgx.Tx, productIDs []int) error {
+	if len(productIDs) == 0 {
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM price_history WHERE product_id = ANY($1)`, productIDs); err != nil {
+		return fmt.Errorf("failed to clear price history: %w", err)
+	}
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO price_history (product_id, price, effective_from, effective_to)
+		SELECT product_id, price, effective_from,
+		       LEAD(effective_from) OVER (PARTITION BY product_id ORDER BY effective_from)
+		FROM (
+			SELECT product_id, price, create_date AS effective_from,
+			       LAG(price) OVER (PARTITION BY product_id ORDER BY create_date) AS previous_price
+			FROM (
+				SELECT DISTINCT ON (product_id, create_date) product_id, price, create_date
+				FROM prices
+				WHERE product_id = ANY($1) AND deleted_at IS NULL
+				ORDER BY product_id, create_date, id DESC
+			) daily
+		) changes
+		WHERE previous_price IS DISTINCT FROM price
+	`, productIDs)
+	if err != nil {
+		return fmt.Errorf("failed to write price history: %w", err)
+	}
+	return nil
+}
+
+// collectProductIDs adds the product ids read from the rows of a query to a set
+// and returns the number of rows.
+func collectProductIDs(rows pgx.Rows, into map[int]bool) (int64, error) {
+	defer rows.Close()
+
+	var count int64
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return 0, fmt.Errorf("failed to scan product id: %w", err)
+		}
+		into[id] = true
+		count++
+	}
+	if rows.Err() != nil {
+		return 0, fmt.Errorf("error during rows iteration: %w", rows.Err())
+	}
+	return count, nil
+}
+
+// idList returns the keys of a set of ids.
+func idList(set map[int]bool) []int {
+	ids := make([]int, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// GetPriceHistory returns the price periods of a product in chronological order.
+func (kp *DBKeeper) GetPriceHistory(ctx context.Context, productID int) (*models.PriceHistory, error) {
+	if kp.pool == nil {
+		return nil, fmt.Errorf("database connection pool is nil")
+	}
+
+	history := &models.PriceHistory{Periods: []models.PricePeriod{}}
+	err := kp.pool.QueryRow(ctx, `
+		SELECT id, name, category FROM products WHERE id = $1
+	`, productID).Scan(&history.Product.ID, &history.Product.Name, &history.Product.Category)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+
+	rows, err := kp.pool.Query(ctx, `
+		SELECT price, effective_from, effective_to
+		FROM price_history
+		WHERE product_id = $1
+		ORDER BY effective_from
+	`, productID)
+	if err != nil {
+		kp.log.Error("Failed to execute query", zap.Error(err))
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var period models.PricePeriod
+		if err := rows.Scan(&period.Price, &period.EffectiveFrom, &period.EffectiveTo); err != nil {
+			kp.log.Error("Failed to scan row", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		history.Periods = append(history.Periods, period)
+	}
+
+	if rows.Err() != nil {
+		kp.log.Error("Error occurred during rows iteration", zap.Error(rows.Err()))
+		return nil, fmt.Errorf("error during rows iteration: %w", rows.Err())
+	}
+
+	return history, nil
+}
@@ -0,0 +1,110 @@
+package dbkeeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"github.com/drstein77/priceanalyzer/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// GetPriceIndex computes a Laspeyres price index of a basket from the base period on.
+//
+// The price of a product in a period is the average of its live prices dated in
+// that period; a period without prices carries the last known price forward. The
+// index of a period is the weighted cost of the basket relative to its cost in the
+// base period, times 100. Products without a price in the base period are left out
+// and reported as excluded.
+func (kp *DBKeeper) GetPriceIndex(ctx context.Context, query models.IndexQuery) (*models.PriceIndex, error) {
+	index := &models.PriceIndex{Interval: query.Interval, Excluded: []int{}, Points: []models.IndexPoint{}}
+	err := kp.inTx(ctx, func(tx pgx.Tx) error {
+		var basketID int
+		err := tx.QueryRow(ctx, `SELECT id, name FROM baskets WHERE name = $1`, query.Basket).Scan(&basketID, &index.Basket)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return storage.ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get basket: %w", err)
+		}
+
+		err = tx.QueryRow(ctx, `SELECT DATE_TRUNC($1, $2::timestamp)`, query.Interval, query.Base).Scan(&index.Base)
+		if err != nil {
+			return fmt.Errorf("failed to determine base period: %w", err)
+		}
+
+		// Price per product and period, for the products of the basket
+		observations := `
+			SELECT product_id, DATE_TRUNC($2, create_date) AS period, AVG(price) AS price
+			FROM prices
+			WHERE deleted_at IS NULL AND product_id IN (SELECT product_id FROM basket_items WHERE basket_id = $1)
+			GROUP BY 1, 2
+		`
+
+		rows, err := tx.Query(ctx, `
+			WITH obs AS (`+observations+`)
+			SELECT product_id FROM basket_items
+			WHERE basket_id = $1
+			  AND product_id NOT IN (SELECT product_id FROM obs WHERE period = $3)
+			ORDER BY product_id
+		`, basketID, query.Interval, index.Base)
+		if err != nil {
+			return fmt.Errorf("failed to find excluded products: %w", err)
+		}
+		index.Excluded, err = pgx.CollectRows(rows, pgx.RowTo[int])
+		if err != nil {
+			return fmt.Errorf("failed to scan excluded products: %w", err)
+		}
+
+		rows, err = tx.Query(ctx, `
+			WITH obs AS (`+observations+`),
+			base AS (
+				SELECT i.product_id, i.weight, obs.price AS base_price
+				FROM basket_items i
+				JOIN obs ON obs.product_id = i.product_id AND obs.period = $3
+				WHERE i.basket_id = $1
+			),
+			periods AS (
+				SELECT GENERATE_SERIES($3::timestamp, (SELECT MAX(period) FROM obs), ('1 ' || $2)::interval) AS period
+			),
+			costs AS (
+				SELECT periods.period,
+				       SUM(base.weight * (
+				           SELECT price FROM obs
+				           WHERE obs.product_id = base.product_id AND obs.period <= periods.period
+				           ORDER BY obs.period DESC
+				           LIMIT 1
+				       )) AS cost,
+				       SUM(base.weight * base.base_price) AS base_cost
+				FROM periods CROSS JOIN base
+				GROUP BY periods.period
+			)
+			SELECT period,
+			       ROUND(cost / base_cost * 100, 2)::float8,
+			       ROUND((cost / LAG(cost) OVER (ORDER BY period) - 1) * 100, 2)::float8
+			FROM costs
+			WHERE base_cost > 0
+			ORDER BY period
+		`, basketID, query.Interval, index.Base)
+		if err != nil {
+			return fmt.Errorf("failed to compute index: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var point models.IndexPoint
+			if err := rows.Scan(&point.Period, &point.Index, &point.Change); err != nil {
+				return fmt.Errorf("failed to scan row: %w", err)
+			}
+			index.Points = append(index.Points, point)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		kp.log.Error("Failed to compute price index", zap.Error(err))
+		return nil, err
+	}
+	return index, nil
+}
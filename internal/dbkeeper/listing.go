@@ -0,0 +1,77 @@
+package dbkeeper
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"go.uber.org/zap"
+)
+
+// sortColumns maps listing sort fields to their columns and SQL types.
+var sortColumns = map[string]struct {
+	column string
+	cast   string
+}{
+	"id":          {"id", "integer"},
+	"price":       {"price", "numeric"},
+	"name":        {"name", "text"},
+	"category":    {"category", "text"},
+	"create_date": {"create_date", "timestamp"},
+}
+
+// ListProducts returns filtered products ordered by the sort field and id,
+// starting right after the cursor when one is given.
+func (kp *DBKeeper) ListProducts(ctx context.Context, query models.ListQuery) ([]models.Product, error) {
+	if kp.pool == nil {
+		return nil, fmt.Errorf("database connection pool is nil")
+	}
+
+	sort, ok := sortColumns[query.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort field %q", query.Sort)
+	}
+
+	direction, compare := "ASC", ">"
+	if query.Desc {
+		direction, compare = "DESC", "<"
+	}
+
+	var b whereBuilder
+	b.filter(query.Filter)
+	if query.After != nil {
+		b.add(fmt.Sprintf("(%s, id) %s (?::%s, ?)", sort.column, compare, sort.cast), query.After.Key, query.After.ID)
+	}
+
+	sql := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		%s
+		ORDER BY %s %s, id %s
+		LIMIT %s
+	`, productColumns, b.source(query.Filter), b.where(), sort.column, direction, direction, b.arg(query.Limit))
+
+	rows, err := kp.pool.Query(ctx, sql, b.args...)
+	if err != nil {
+		kp.log.Error("Failed to execute query", zap.Error(err))
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	var products []models.Product
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			kp.log.Error("Failed to scan row", zap.Error(err))
+			return nil, err
+		}
+		products = append(products, *product)
+	}
+
+	if rows.Err() != nil {
+		kp.log.Error("Error occurred during rows iteration", zap.Error(rows.Err()))
+		return nil, fmt.Errorf("error during rows iteration: %w", rows.Err())
+	}
+
+	return products, nil
+}
@@ -0,0 +1,154 @@
+package dbkeeper
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"go.uber.org/zap"
+)
+
+// FindOutliers returns the products matching the filter whose prices stand out by
+// any of the requested methods, ordered by method and by how far they stand out.
+//
+// The z-score and IQR methods compare a price with the other prices of its category
+// within the filter. The history method compares it with the median of all live
+// prices of the same product, for products with at least three of them.
+func (kp *DBKeeper) FindOutliers(ctx context.Context, query models.OutlierQuery) ([]models.Outlier, error) {
+	if kp.pool == nil {
+		return nil, fmt.Errorf("database connection pool is nil")
+	}
+
+	var b whereBuilder
+	b.filter(query.Filter)
+	source := b.source(query.Filter)
+	where := b.where()
+
+	var parts []string
+	for _, method := range query.Methods {
+		switch method {
+		case models.OutlierZScore:
+			parts = append(parts, fmt.Sprintf(`
+				SELECT %[1]s, 'zscore' AS method, ((price - mean) / sd)::float8 AS score, mean::float8 AS expected
+				FROM (
+					SELECT *, AVG(price) OVER w AS mean, STDDEV_SAMP(price) OVER w AS sd
+					FROM filtered
+					WINDOW w AS (PARTITION BY category)
+				) z
+				WHERE sd > 0 AND ABS(price - mean) / sd > %[2]s
+			`, productColumns, b.arg(query.ZScore)))
+		case models.OutlierIQR:
+			parts = append(parts, fmt.Sprintf(`
+				SELECT %[1]s, 'iqr' AS method,
+				       (CASE WHEN price > q3 THEN price - q3 ELSE price - q1 END / (q3 - q1))::float8 AS score,
+				       median::float8 AS expected
+				FROM filtered
+				JOIN (
+					SELECT category AS q_category,
+					       PERCENTILE_CONT(0.25) WITHIN GROUP (ORDER BY price) AS q1,
+					       PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY price) AS median,
+					       PERCENTILE_CONT(0.75) WITHIN GROUP (ORDER BY price) AS q3
+					FROM filtered
+					GROUP BY category
+				) q ON q.q_category IS NOT DISTINCT FROM filtered.category
+				WHERE q3 > q1 AND (price > q3 + %[2]s * (q3 - q1) OR price < q1 - %[2]s * (q3 - q1))
+			`, productColumns, b.arg(query.IQRFactor)))
+		case models.OutlierHistory:
+			parts = append(parts, fmt.Sprintf(`
+				SELECT %[1]s, 'history' AS method, ((price - typical) / typical * 100)::float8 AS score,
+				       typical::float8 AS expected
+				FROM filtered
+				JOIN (
+					SELECT product_id AS h_product_id, PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY price) AS typical
+					FROM prices
+					WHERE deleted_at IS NULL
+					GROUP BY product_id
+					HAVING COUNT(*) >= 3
+				) h ON h.h_product_id = filtered.product_id
+				WHERE typical > 0 AND ABS(price - typical) / typical * 100 > %[2]s
+			`, productColumns, b.arg(query.Deviation)))
+		default:
+			return nil, fmt.Errorf("unsupported outlier method %q", method)
+		}
+	}
+
+	sql := fmt.Sprintf(`
+		WITH filtered AS (
+			SELECT * FROM %s %s
+		)
+		SELECT * FROM (%s) outliers
+		ORDER BY method, ABS(score) DESC, id
+		LIMIT %s
+	`, source, where, strings.Join(parts, " UNION ALL "), b.arg(query.Limit))
+
+	rows, err := kp.pool.Query(ctx, sql, b.args...)
+	if err != nil {
+		kp.log.Error("Failed to execute query", zap.Error(err))
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	outliers := []models.Outlier{}
+	for rows.Next() {
+		var o models.Outlier
+		p := &o.Product
+		err := rows.Scan(&p.ID, &p.ProductID, &p.Name, &p.Category, &p.Price, &p.CreatedAt, &p.DeletedAt,
+			&o.Method, &o.Score, &o.Expected)
+		if err != nil {
+			kp.log.Error("Failed to scan row", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		outliers = append(outliers, o)
+	}
+
+	if rows.Err() != nil {
+		kp.log.Error("Error occurred during rows iteration", zap.Error(rows.Err()))
+		return nil, fmt.Errorf("error during rows iteration: %w", rows.Err())
+	}
+	return outliers, nil
+}
+
+// CurrentPrices returns the current price of each product, in the order given.
+// Products that are unknown or have no live price get nil.
+func (kp *DBKeeper) CurrentPrices(ctx context.Context, products []models.Product) ([]*float64, error) {
+	if kp.pool == nil {
+		return nil, fmt.Errorf("database connection pool is nil")
+	}
+
+	names := make([]string, len(products))
+	categories := make([]string, len(products))
+	for i, product := range products {
+		names[i] = product.Name
+		categories[i] = product.Category
+	}
+
+	rows, err := kp.pool.Query(ctx, `
+		SELECT keys.n, ph.price::float8
+		FROM unnest($1::text[], $2::text[]) WITH ORDINALITY AS keys (name, category, n)
+		JOIN products p ON p.name = keys.name AND p.category = keys.category
+		JOIN price_history ph ON ph.product_id = p.id AND ph.effective_to IS NULL
+	`, names, categories)
+	if err != nil {
+		kp.log.Error("Failed to execute query", zap.Error(err))
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	prices := make([]*float64, len(products))
+	for rows.Next() {
+		var n int
+		var price float64
+		if err := rows.Scan(&n, &price); err != nil {
+			kp.log.Error("Failed to scan row", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		prices[n-1] = &price
+	}
+
+	if rows.Err() != nil {
+		kp.log.Error("Error occurred during rows iteration", zap.Error(rows.Err()))
+		return nil, fmt.Errorf("error during rows iteration: %w", rows.Err())
+	}
+	return prices, nil
+}
@@ -0,0 +1,268 @@
+package dbkeeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"github.com/drstein77/priceanalyzer/internal/storage"
+	"github.com/jackc/pgx/v5"
+)
+
+// GetProduct returns the product with the given id or storage.ErrNotFound.
+func (kp *DBKeeper) GetProduct(ctx context.Context, id int) (*models.Product, error) {
+	if kp.pool == nil {
+		return nil, fmt.Errorf("database connection pool is nil")
+	}
+
+	row := kp.pool.QueryRow(ctx, `
+		SELECT `+productColumns+`
+		FROM prices
+		WHERE id = $1
+	`, id)
+	return scanProduct(row)
+}
+
+// CreateProduct stores a new product and returns it with its id.
+// An identical product yields storage.ErrConflict. With createCategory set, a
+// category that does not exist yet is created in the same transaction.
+func (kp *DBKeeper) CreateProduct(ctx context.Context, product models.Product, createCategory bool) (*models.Product, error) {
+	var created *models.Product
+	err := kp.inTx(ctx, func(tx pgx.Tx) error {
+		if createCategory {
+			var err error
+			if product.Category, err = registerCategory(ctx, tx, product.Category); err != nil {
+				return err
+			}
+		}
+		if err := checkDuplicate(ctx, tx, product); err != nil {
+			return err
+		}
+
+		productID, err := resolveProduct(ctx, tx, product)
+		if err != nil {
+			return err
+		}
+
+		row := tx.QueryRow(ctx, `
+			INSERT INTO prices (product_id, name, category, price, create_date)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING `+productColumns+`
+		`, productID, product.Name, product.Category, product.Price, product.CreatedAt)
+
+		created, err = scanProduct(row)
+		if err != nil {
+			return err
+		}
+		return refreshHistory(ctx, tx, []int{productID})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// UpdateProduct replaces all fields of an existing product.
+// With createCategory set, a category that does not exist yet is created in the same transaction.
+func (kp *DBKeeper) UpdateProduct(ctx context.Context, product models.Product, createCategory bool) (*models.Product, error) {
+	var updated *models.Product
+	err := kp.inTx(ctx, func(tx pgx.Tx) error {
+		current, err := lockProduct(ctx, tx, product.ID)
+		if err != nil {
+			return err
+		}
+		if createCategory {
+			if product.Category, err = registerCategory(ctx, tx, product.Category); err != nil {
+				return err
+			}
+		}
+
+		updated, err = updateProduct(ctx, tx, current.ProductID, product)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// PatchProduct updates the fields of an existing product that are set in the patch.
+// With createCategory set, a category that does not exist yet is created in the same transaction.
+func (kp *DBKeeper) PatchProduct(ctx context.Context, id int, patch models.ProductPatch, createCategory bool) (*models.Product, error) {
+	var updated *models.Product
+	err := kp.inTx(ctx, func(tx pgx.Tx) error {
+		product, err := lockProduct(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		previousProductID := product.ProductID
+
+		if patch.Name != nil {
+			product.Name = *patch.Name
+		}
+		if patch.Category != nil {
+			product.Category = *patch.Category
+			if createCategory {
+				if product.Category, err = registerCategory(ctx, tx, product.Category); err != nil {
+					return err
+				}
+			}
+		}
+		if patch.Price != nil {
+			product.Price = *patch.Price
+		}
+		if patch.CreatedAt != nil {
+			product.CreatedAt = *patch.CreatedAt
+		}
+
+		updated, err = updateProduct(ctx, tx, previousProductID, *product)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeleteProduct moves the product with the given id to the trash or returns storage.ErrNotFound.
+func (kp *DBKeeper) DeleteProduct(ctx context.Context, id int) error {
+	return kp.inTx(ctx, func(tx pgx.Tx) error {
+		var productID int
+		err := tx.QueryRow(ctx, `
+			UPDATE prices SET deleted_at = NOW()
+			WHERE id = $1 AND deleted_at IS NULL
+			RETURNING product_id
+		`, id).Scan(&productID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return storage.ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to delete product: %w", err)
+		}
+		return refreshHistory(ctx, tx, []int{productID})
+	})
+}
+
+// RestoreProduct takes a deleted product out of the trash.
+// It returns storage.ErrConflict if the product is not deleted.
+func (kp *DBKeeper) RestoreProduct(ctx context.Context, id int) (*models.Product, error) {
+	var restored *models.Product
+	err := kp.inTx(ctx, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			SELECT `+productColumns+`
+			FROM prices
+			WHERE id = $1
+			FOR UPDATE
+		`, id)
+		product, err := scanProduct(row)
+		if err != nil {
+			return err
+		}
+		if product.DeletedAt == nil {
+			return storage.ErrConflict
+		}
+
+		product.DeletedAt = nil
+		if err := checkDuplicate(ctx, tx, *product); err != nil {
+			return err
+		}
+
+		row = tx.QueryRow(ctx, `
+			UPDATE prices SET deleted_at = NULL
+			WHERE id = $1
+			RETURNING `+productColumns, id)
+		restored, err = scanProduct(row)
+		if err != nil {
+			return err
+		}
+		return refreshHistory(ctx, tx, []int{restored.ProductID})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// lockProduct reads a product that is not deleted and locks its row until the end of the transaction.
+func lockProduct(ctx context.Context, tx pgx.Tx, id int) (*models.Product, error) {
+	row := tx.QueryRow(ctx, `
+		SELECT `+productColumns+`
+		FROM prices
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE
+	`, id)
+	return scanProduct(row)
+}
+
+// updateProduct writes all fields of a product unless another row already holds the same values,
+// and refreshes the price history of the product the row belonged to and the one it belongs to now.
+func updateProduct(ctx context.Context, tx pgx.Tx, previousProductID int, product models.Product) (*models.Product, error) {
+	if err := checkDuplicate(ctx, tx, product); err != nil {
+		return nil, err
+	}
+
+	productID, err := resolveProduct(ctx, tx, product)
+	if err != nil {
+		return nil, err
+	}
+
+	row := tx.QueryRow(ctx, `
+		UPDATE prices
+		SET product_id = $2, name = $3, category = $4, price = $5, create_date = $6
+		WHERE id = $1
+		RETURNING `+productColumns+`
+	`, product.ID, productID, product.Name, product.Category, product.Price, product.CreatedAt)
+	updated, err := scanProduct(row)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := refreshHistory(ctx, tx, idList(map[int]bool{previousProductID: true, productID: true})); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// checkDuplicate returns storage.ErrConflict if a live row other than the product
+// itself has the same name, category, price and date.
+func checkDuplicate(ctx context.Context, tx pgx.Tx, product models.Product) error {
+	var exists bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM prices
+			WHERE name = $1 AND category = $2 AND price = $3 AND create_date = $4 AND id <> $5
+				AND deleted_at IS NULL
+		)
+	`, product.Name, product.Category, product.Price, product.CreatedAt, product.ID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check for duplicates: %w", err)
+	}
+	if exists {
+		return storage.ErrConflict
+	}
+	return nil
+}
+
+// productColumns lists the columns read by scanProduct.
+const productColumns = `id, product_id, name, category, price, create_date, deleted_at`
+
+func scanProduct(row pgx.Row) (*models.Product, error) {
+	var product models.Product
+	err := row.Scan(
+		&product.ID,
+		&product.ProductID,
+		&product.Name,
+		&product.Category,
+		&product.Price,
+		&product.CreatedAt,
+		&product.DeletedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan row: %w", err)
+	}
+	return &product, nil
+}
@@ -0,0 +1,134 @@
+package dbkeeper
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// rowQuerier is implemented by both the connection pool and a transaction.
+type rowQuerier interface {
+	QueryRow(context.Context, string, ...any) pgx.Row
+}
+
+// GetStats returns the totals of the products matching the filter.
+func (kp *DBKeeper) GetStats(ctx context.Context, filter models.ProductFilter) (*models.ProcessResponse, error) {
+	if kp.pool == nil {
+		return nil, fmt.Errorf("database connection pool is nil")
+	}
+
+	stats, err := queryStats(ctx, kp.pool, filter)
+	if err != nil {
+		kp.log.Error("Failed to calculate stats", zap.Error(err))
+		return nil, err
+	}
+	return stats, nil
+}
+
+// queryStats counts the products matching the filter, their categories and their total price.
+// Without a filter the totals are read from the global aggregates, and with a category
+// filter alone from the per-category ones; triggers on prices keep both up to date.
+// Other filters need a scan.
+func queryStats(ctx context.Context, q rowQuerier, filter models.ProductFilter) (*models.ProcessResponse, error) {
+	var b whereBuilder
+	var sql string
+
+	switch byCategory := (models.ProductFilter{Category: filter.Category}); {
+	case filter == models.ProductFilter{}:
+		sql = `
+			SELECT COALESCE(SUM(items), 0)::bigint, COALESCE(SUM(categories), 0)::bigint, COALESCE(SUM(total), 0)::float8
+			FROM price_totals
+		`
+	case filter == byCategory:
+		// Only the category condition applies to the aggregate table
+		b.filter(models.ProductFilter{Category: filter.Category, IncludeDeleted: true})
+		b.add("items > 0")
+		sql = fmt.Sprintf(`
+			SELECT COALESCE(SUM(items), 0)::bigint, COUNT(*), COALESCE(SUM(total), 0)::float8
+			FROM category_totals
+			%s
+		`, b.where())
+	default:
+		b.filter(filter)
+		sql = fmt.Sprintf(`
+			SELECT COUNT(*), COUNT(DISTINCT category), COALESCE(SUM(price), 0)
+			FROM %s
+			%s
+		`, b.source(filter), b.where())
+	}
+
+	var stats models.ProcessResponse
+	if err := q.QueryRow(ctx, sql, b.args...).Scan(&stats.TotalItems, &stats.TotalCategories, &stats.TotalPrice); err != nil {
+		return nil, fmt.Errorf("failed to calculate stats: %w", err)
+	}
+	return &stats, nil
+}
+
+// GetCategoryStats returns price statistics per category for the products matching the filter.
+// With rollup set, subcategories are counted under their top-level category.
+func (kp *DBKeeper) GetCategoryStats(ctx context.Context, filter models.ProductFilter, rollup bool) ([]models.CategoryStats, error) {
+	if kp.pool == nil {
+		return nil, fmt.Errorf("database connection pool is nil")
+	}
+
+	var b whereBuilder
+	b.filter(filter)
+
+	group := "COALESCE(filtered.category, '')"
+	join := ""
+	if rollup {
+		group = "COALESCE(roots.root, filtered.category, '')"
+		join = `LEFT JOIN (
+			WITH RECURSIVE tree AS (
+				SELECT id, name, name AS root FROM categories WHERE parent_id IS NULL
+				UNION ALL
+				SELECT c.id, c.name, tree.root FROM categories c JOIN tree ON c.parent_id = tree.id
+			)
+			SELECT name, root FROM tree
+		) roots ON roots.name = filtered.category`
+	}
+
+	sql := fmt.Sprintf(`
+		SELECT %[1]s AS category,
+		       COUNT(*),
+		       MIN(price)::float8,
+		       MAX(price)::float8,
+		       ROUND(AVG(price), 2)::float8,
+		       ROUND(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY price)::numeric, 2)::float8,
+		       ROUND(PERCENTILE_CONT(0.9) WITHIN GROUP (ORDER BY price)::numeric, 2)::float8,
+		       ROUND(PERCENTILE_CONT(0.95) WITHIN GROUP (ORDER BY price)::numeric, 2)::float8,
+		       ROUND(COALESCE(STDDEV_SAMP(price), 0), 2)::float8,
+		       SUM(price)::float8
+		FROM (SELECT category, price FROM %[2]s %[3]s) filtered
+		%[4]s
+		GROUP BY 1
+		ORDER BY 1
+	`, group, b.source(filter), b.where(), join)
+
+	rows, err := kp.pool.Query(ctx, sql, b.args...)
+	if err != nil {
+		kp.log.Error("Failed to execute query", zap.Error(err))
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []models.CategoryStats{}
+	for rows.Next() {
+		var s models.CategoryStats
+		err := rows.Scan(&s.Category, &s.Count, &s.Min, &s.Max, &s.Mean, &s.Median, &s.P90, &s.P95, &s.StdDev, &s.Sum)
+		if err != nil {
+			kp.log.Error("Failed to scan row", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		stats = append(stats, s)
+	}
+
+	if rows.Err() != nil {
+		kp.log.Error("Error occurred during rows iteration", zap.Error(rows.Err()))
+		return nil, fmt.Errorf("error during rows iteration: %w", rows.Err())
+	}
+	return stats, nil
+}
@@ -0,0 +1,96 @@
+package dbkeeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"github.com/drstein77/priceanalyzer/internal/storage"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+// GetTimeSeries aggregates the products matching the filter per day, week or month.
+// Bucket boundaries follow the time zone of the query, create dates being stored in UTC.
+// Buckets without prices between the first and last bucket are filled in, and the
+// range extends to the start and end of the filter when they are set. A time zone
+// PostgreSQL does not know is reported as storage.ErrInvalidQuery.
+func (kp *DBKeeper) GetTimeSeries(ctx context.Context, query models.TimeSeriesQuery) ([]models.TimeBucket, error) {
+	if kp.pool == nil {
+		return nil, fmt.Errorf("database connection pool is nil")
+	}
+
+	var b whereBuilder
+	b.filter(query.Filter)
+
+	interval := b.arg(query.Interval)
+	zone := b.arg(query.TimeZone)
+	local := func(ts string) string {
+		return fmt.Sprintf("DATE_TRUNC(%s, %s AT TIME ZONE 'UTC' AT TIME ZONE %s)", interval, ts, zone)
+	}
+
+	first, last := "NULL::timestamp", "NULL::timestamp"
+	if query.Filter.Start != nil {
+		first = local(b.arg(*query.Filter.Start) + "::timestamp")
+	}
+	if query.Filter.End != nil {
+		last = local(b.arg(*query.Filter.End) + "::timestamp")
+	}
+
+	sql := fmt.Sprintf(`
+		WITH filtered AS (
+			SELECT %[1]s AS bucket, price
+			FROM %[2]s
+			%[3]s
+		), span AS (
+			SELECT COALESCE(%[4]s, MIN(bucket)) AS first, COALESCE(%[5]s, MAX(bucket)) AS last
+			FROM filtered
+		), bounds AS (
+			SELECT first, LEAST(last, first + %[8]s::float8 * ('1 ' || %[7]s)::interval) AS last
+			FROM span
+		)
+		SELECT series.bucket AT TIME ZONE %[6]s,
+		       COUNT(filtered.price),
+		       ROUND(AVG(filtered.price), 2)::float8,
+		       MIN(filtered.price)::float8,
+		       MAX(filtered.price)::float8
+		FROM bounds
+		CROSS JOIN GENERATE_SERIES(bounds.first, bounds.last, ('1 ' || %[7]s)::interval) AS series (bucket)
+		LEFT JOIN filtered ON filtered.bucket = series.bucket
+		GROUP BY series.bucket
+		ORDER BY series.bucket
+	`, local("create_date"), b.source(query.Filter), b.where(), first, last, zone, interval, b.arg(query.Limit))
+
+	rows, err := kp.pool.Query(ctx, sql, b.args...)
+	if err != nil {
+		kp.log.Error("Failed to execute query", zap.Error(err))
+		return nil, timeSeriesError(fmt.Errorf("failed to execute query: %w", err), query.TimeZone)
+	}
+	defer rows.Close()
+
+	buckets := []models.TimeBucket{}
+	for rows.Next() {
+		var bucket models.TimeBucket
+		if err := rows.Scan(&bucket.Start, &bucket.Count, &bucket.Avg, &bucket.Min, &bucket.Max); err != nil {
+			kp.log.Error("Failed to scan row", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	if rows.Err() != nil {
+		kp.log.Error("Error occurred during rows iteration", zap.Error(rows.Err()))
+		return nil, timeSeriesError(fmt.Errorf("error during rows iteration: %w", rows.Err()), query.TimeZone)
+	}
+	return buckets, nil
+}
+
+// timeSeriesError turns the rejection of an unknown time zone into storage.ErrInvalidQuery.
+func timeSeriesError(err error, zone string) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "22023" {
+		return fmt.Errorf("%w: unknown time zone %q", storage.ErrInvalidQuery, zone)
+	}
+	return err
+}
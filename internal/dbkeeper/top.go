@@ -0,0 +1,70 @@
+package dbkeeper
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"go.uber.org/zap"
+)
+
+// TopProducts ranks the products matching the filter by price and returns the first N,
+// overall or per category. Each product takes part with its highest price when ranking
+// in descending order and with its lowest one otherwise.
+func (kp *DBKeeper) TopProducts(ctx context.Context, query models.TopQuery) ([]models.RankedProduct, error) {
+	if kp.pool == nil {
+		return nil, fmt.Errorf("database connection pool is nil")
+	}
+
+	direction := "ASC"
+	if query.Desc {
+		direction = "DESC"
+	}
+	partition, order := "", "rank"
+	if query.Per == "category" {
+		partition, order = "PARTITION BY category", "category, rank"
+	}
+
+	var b whereBuilder
+	b.filter(query.Filter)
+
+	sql := fmt.Sprintf(`
+		SELECT %[1]s, rank
+		FROM (
+			SELECT *, ROW_NUMBER() OVER (%[2]s ORDER BY price %[3]s, id) AS rank
+			FROM (
+				SELECT DISTINCT ON (product_id) *
+				FROM %[4]s
+				%[5]s
+				ORDER BY product_id, price %[3]s, id
+			) extremes
+		) ranked
+		WHERE rank <= %[6]s
+		ORDER BY %[7]s
+	`, productColumns, partition, direction, b.source(query.Filter), b.where(), b.arg(query.N), order)
+
+	rows, err := kp.pool.Query(ctx, sql, b.args...)
+	if err != nil {
+		kp.log.Error("Failed to execute query", zap.Error(err))
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	ranking := []models.RankedProduct{}
+	for rows.Next() {
+		var r models.RankedProduct
+		p := &r.Product
+		err := rows.Scan(&p.ID, &p.ProductID, &p.Name, &p.Category, &p.Price, &p.CreatedAt, &p.DeletedAt, &r.Rank)
+		if err != nil {
+			kp.log.Error("Failed to scan row", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		ranking = append(ranking, r)
+	}
+
+	if rows.Err() != nil {
+		kp.log.Error("Error occurred during rows iteration", zap.Error(rows.Err()))
+		return nil, fmt.Errorf("error during rows iteration: %w", rows.Err())
+	}
+	return ranking, nil
+}
@@ -0,0 +1,47 @@
+package dbkeeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// inTx runs fn in a transaction that is committed when fn succeeds and rolled back otherwise.
+func (kp *DBKeeper) inTx(ctx context.Context, fn func(pgx.Tx) error) error {
+	return kp.inTxWith(ctx, pgx.TxOptions{}, fn)
+}
+
+// inSnapshot runs fn in a read-only repeatable read transaction, so that all of
+// its queries see the same data.
+func (kp *DBKeeper) inSnapshot(ctx context.Context, fn func(pgx.Tx) error) error {
+	return kp.inTxWith(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}, fn)
+}
+
+// inTxWith runs fn in a transaction with the given options, committed when fn
+// succeeds and rolled back otherwise.
+func (kp *DBKeeper) inTxWith(ctx context.Context, opts pgx.TxOptions, fn func(pgx.Tx) error) error {
+	if kp.pool == nil {
+		return fmt.Errorf("database connection pool is nil")
+	}
+
+	tx, err := kp.pool.BeginTx(ctx, opts)
+	if err != nil {
+		kp.log.Error("Failed to begin transaction", zap.Error(err))
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := fn(tx); err != nil {
+		if rollbackErr := tx.Rollback(ctx); rollbackErr != nil && !errors.Is(rollbackErr, pgx.ErrTxClosed) {
+			kp.log.Error("Failed to rollback transaction", zap.Error(rollbackErr))
+		}
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
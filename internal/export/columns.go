@@ -0,0 +1,112 @@
+package export
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+)
+
+const dateLayout = "2006-01-02"
+
+// fields lists the exportable product fields in the order of the upload format.
+var fields = []string{"id", "name", "category", "price", "create_date"}
+
+// Column is an exported product field together with its header.
+type Column struct {
+	Field  string
+	Header string
+}
+
+// Options controls the layout of an export file.
+type Options struct {
+	// Columns lists the exported fields in order; all fields are exported when empty.
+	Columns []Column
+	// NoHeader omits the header row. JSON formats then encode rows as arrays.
+	NoHeader bool
+}
+
+// columns returns the configured columns or the default ones.
+func (o Options) columns() []Column {
+	if len(o.Columns) > 0 {
+		return o.Columns
+	}
+	columns, _ := NewColumns(nil, nil)
+	return columns
+}
+
+// NewColumns selects and orders the exported fields and renames their headers.
+// All fields are selected when none are given.
+func NewColumns(selected []string, headers map[string]string) ([]Column, error) {
+	if len(selected) == 0 {
+		selected = fields
+	}
+
+	columns := make([]Column, 0, len(selected))
+	seen := make(map[string]bool, len(selected))
+	for _, field := range selected {
+		if !isField(field) {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+
+		header := field
+		if renamed, ok := headers[field]; ok {
+			header = renamed
+		}
+		if header == "" || strings.ContainsAny(header, ",\"\r\n") {
+			return nil, fmt.Errorf("invalid header %q for field %s", header, field)
+		}
+		if seen[header] {
+			return nil, fmt.Errorf("duplicate column %q", header)
+		}
+		seen[header] = true
+
+		columns = append(columns, Column{Field: field, Header: header})
+	}
+
+	for field := range headers {
+		if !isField(field) {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+	}
+
+	return columns, nil
+}
+
+func isField(name string) bool {
+	for _, field := range fields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// value returns the exported value of the column for a product.
+func (c Column) value(product models.Product) any {
+	switch c.Field {
+	case "id":
+		return product.ID
+	case "name":
+		return product.Name
+	case "category":
+		return product.Category
+	case "price":
+		return product.Price
+	default:
+		return product.CreatedAt.Format(dateLayout)
+	}
+}
+
+// text returns the exported value of the column for a product as text.
+func (c Column) text(product models.Product) string {
+	switch value := c.value(product).(type) {
+	case int:
+		return strconv.Itoa(value)
+	case float64:
+		return strconv.FormatFloat(value, 'f', 2, 64)
+	default:
+		return value.(string)
+	}
+}
@@ -0,0 +1,49 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+)
+
+// comparisonHeader is the header row of a period comparison CSV file.
+var comparisonHeader = []string{
+	"category",
+	"a_count", "a_total", "a_average",
+	"b_count", "b_total", "b_average",
+	"count_change", "total_change", "total_change_percent", "average_change", "average_change_percent",
+}
+
+// WriteComparisonCSV writes per-category period comparisons as CSV rows preceded by a header.
+// Missing averages and percent changes are written as empty cells.
+func WriteComparisonCSV(w io.Writer, comparisons []models.CategoryComparison) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(comparisonHeader); err != nil {
+		return err
+	}
+
+	for _, c := range comparisons {
+		record := []string{
+			c.Category,
+			strconv.FormatInt(c.A.Count, 10),
+			formatAmount(c.A.Total),
+			formatOptionalAmount(c.A.Average),
+			strconv.FormatInt(c.B.Count, 10),
+			formatAmount(c.B.Total),
+			formatOptionalAmount(c.B.Average),
+			strconv.FormatInt(c.CountChange, 10),
+			formatAmount(c.TotalChange),
+			formatOptionalAmount(c.TotalChangePercent),
+			formatOptionalAmount(c.AverageChange),
+			formatOptionalAmount(c.AverageChangePercent),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,59 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+)
+
+// CSVWriter writes products as CSV rows preceded by a header.
+type CSVWriter struct {
+	w           *csv.Writer
+	columns     []Column
+	wroteHeader bool
+}
+
+// NewCSVWriter creates a new CSVWriter.
+func NewCSVWriter(w io.Writer, opts Options) (Writer, error) {
+	return &CSVWriter{
+		w:           csv.NewWriter(w),
+		columns:     opts.columns(),
+		wroteHeader: opts.NoHeader,
+	}, nil
+}
+
+// Write writes a product as a CSV row.
+func (c *CSVWriter) Write(product models.Product) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	record := make([]string, len(c.columns))
+	for i, column := range c.columns {
+		record[i] = column.text(product)
+	}
+	return c.w.Write(record)
+}
+
+// Close writes the header of an empty file and flushes buffered rows.
+func (c *CSVWriter) Close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *CSVWriter) writeHeader() error {
+	if c.wroteHeader {
+		return nil
+	}
+	c.wroteHeader = true
+
+	header := make([]string, len(c.columns))
+	for i, column := range c.columns {
+		header[i] = column.Header
+	}
+	return c.w.Write(header)
+}
@@ -0,0 +1,47 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+)
+
+// priceChangesHeader is the header row of a price changes CSV file.
+var priceChangesHeader = []string{"product_id", "name", "category", "status", "old_price", "new_price", "change", "change_percent"}
+
+// WritePriceChangesCSV writes price changes as CSV rows preceded by a header.
+// Missing prices and changes are written as empty cells.
+func WritePriceChangesCSV(w io.Writer, changes []models.PriceChange) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(priceChangesHeader); err != nil {
+		return err
+	}
+
+	for _, c := range changes {
+		record := []string{
+			strconv.Itoa(c.Product.ID),
+			c.Product.Name,
+			c.Product.Category,
+			c.Status,
+			formatOptionalAmount(c.OldPrice),
+			formatOptionalAmount(c.NewPrice),
+			formatOptionalAmount(c.Change),
+			formatOptionalAmount(c.ChangePercent),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatOptionalAmount(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return formatAmount(*v)
+}
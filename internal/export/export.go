@@ -0,0 +1,110 @@
+package export
+
+import (
+	"context"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+)
+
+// Writer encodes products into an export file.
+type Writer interface {
+	// Write encodes a single product.
+	Write(models.Product) error
+	// Close flushes buffered data and finalizes the file.
+	Close() error
+}
+
+// Format describes an export file format.
+type Format struct {
+	Name        string
+	Extension   string
+	ContentType string
+	NewWriter   func(io.Writer, Options) (Writer, error)
+}
+
+// FileName returns the name of an export file with the given base name.
+func (f Format) FileName(base string) string {
+	return base + "." + f.Extension
+}
+
+// CSV is the default export format, compatible with the upload format.
+var CSV = Format{
+	Name:        "csv",
+	Extension:   "csv",
+	ContentType: "text/csv",
+	NewWriter:   NewCSVWriter,
+}
+
+// formats lists the supported export formats.
+var formats = []Format{
+	CSV,
+	{
+		Name:        "json",
+		Extension:   "json",
+		ContentType: "application/json",
+		NewWriter:   NewJSONWriter,
+	},
+	{
+		Name:        "ndjson",
+		Extension:   "ndjson",
+		ContentType: "application/x-ndjson",
+		NewWriter:   NewNDJSONWriter,
+	},
+	{
+		Name:        "xlsx",
+		Extension:   "xlsx",
+		ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		NewWriter:   NewXLSXWriter,
+	},
+	{
+		Name:        "parquet",
+		Extension:   "parquet",
+		ContentType: "application/vnd.apache.parquet",
+		NewWriter:   NewParquetWriter,
+	},
+}
+
+// Lookup returns the format with the given name.
+func Lookup(name string) (Format, bool) {
+	for _, format := range formats {
+		if strings.EqualFold(format.Name, name) {
+			return format, true
+		}
+	}
+	return Format{}, false
+}
+
+// Negotiate picks the first format listed in an Accept header,
+// falling back to CSV when none of the media types is supported.
+func Negotiate(accept string) Format {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || params["q"] == "0" {
+			continue
+		}
+		for _, format := range formats {
+			if format.ContentType == mediaType {
+				return format
+			}
+		}
+	}
+	return CSV
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the chosen export format.
+func NewContext(ctx context.Context, format Format) context.Context {
+	return context.WithValue(ctx, contextKey{}, format)
+}
+
+// FromContext returns the export format stored in ctx, or CSV if there is none.
+func FromContext(ctx context.Context) Format {
+	if format, ok := ctx.Value(contextKey{}).(Format); ok {
+		return format
+	}
+	return CSV
+}
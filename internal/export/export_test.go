@@ -0,0 +1,406 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"github.com/parquet-go/parquet-go"
+	"github.com/xuri/excelize/v2"
+)
+
+var testProducts = []models.Product{
+	{ID: 1, Name: "Milk", Category: "dairy", Price: 1.5, CreatedAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+	{ID: 2, Name: "Bread, rye", Category: "bakery", Price: 2, CreatedAt: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)},
+}
+
+func mustColumns(t *testing.T, selected []string, headers map[string]string) []Column {
+	t.Helper()
+	columns, err := NewColumns(selected, headers)
+	if err != nil {
+		t.Fatalf("NewColumns: %v", err)
+	}
+	return columns
+}
+
+func writeAll(t *testing.T, format Format, opts Options, products []models.Product) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := format.NewWriter(&buf, opts)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	for _, product := range products {
+		if err := w.Write(product); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestTextWriters(t *testing.T) {
+	selected := []string{"price", "name"}
+	renamed := map[string]string{"price": "cost"}
+
+	tests := []struct {
+		name     string
+		format   string
+		selected []string
+		headers  map[string]string
+		noHeader bool
+		products []models.Product
+		want     string
+	}{
+		{
+			name:     "csv",
+			format:   "csv",
+			products: testProducts,
+			want: "id,name,category,price,create_date\n" +
+				"1,Milk,dairy,1.50,2024-01-02\n" +
+				"2,\"Bread, rye\",bakery,2.00,2024-01-03\n",
+		},
+		{
+			name:     "csv fields and rename",
+			format:   "csv",
+			selected: selected,
+			headers:  renamed,
+			products: testProducts,
+			want:     "cost,name\n1.50,Milk\n2.00,\"Bread, rye\"\n",
+		},
+		{
+			name:     "csv without header",
+			format:   "csv",
+			selected: selected,
+			noHeader: true,
+			products: testProducts,
+			want:     "1.50,Milk\n2.00,\"Bread, rye\"\n",
+		},
+		{
+			name:   "csv empty",
+			format: "csv",
+			want:   "id,name,category,price,create_date\n",
+		},
+		{
+			name:     "csv empty without header",
+			format:   "csv",
+			noHeader: true,
+			want:     "",
+		},
+		{
+			name:     "json",
+			format:   "json",
+			products: testProducts,
+			want: `[{"id":1,"name":"Milk","category":"dairy","price":1.5,"create_date":"2024-01-02"},` +
+				`{"id":2,"name":"Bread, rye","category":"bakery","price":2,"create_date":"2024-01-03"}]` + "\n",
+		},
+		{
+			name:     "json fields and rename",
+			format:   "json",
+			selected: selected,
+			headers:  renamed,
+			products: testProducts,
+			want:     `[{"cost":1.5,"name":"Milk"},{"cost":2,"name":"Bread, rye"}]` + "\n",
+		},
+		{
+			name:     "json without header",
+			format:   "json",
+			selected: selected,
+			noHeader: true,
+			products: testProducts,
+			want:     `[[1.5,"Milk"],[2,"Bread, rye"]]` + "\n",
+		},
+		{
+			name:   "json empty",
+			format: "json",
+			want:   "[]\n",
+		},
+		{
+			name:     "ndjson",
+			format:   "ndjson",
+			products: testProducts,
+			want: `{"id":1,"name":"Milk","category":"dairy","price":1.5,"create_date":"2024-01-02"}` + "\n" +
+				`{"id":2,"name":"Bread, rye","category":"bakery","price":2,"create_date":"2024-01-03"}` + "\n",
+		},
+		{
+			name:     "ndjson fields and rename",
+			format:   "ndjson",
+			selected: selected,
+			headers:  renamed,
+			products: testProducts,
+			want:     `{"cost":1.5,"name":"Milk"}` + "\n" + `{"cost":2,"name":"Bread, rye"}` + "\n",
+		},
+		{
+			name:     "ndjson without header",
+			format:   "ndjson",
+			selected: selected,
+			noHeader: true,
+			products: testProducts,
+			want:     `[1.5,"Milk"]` + "\n" + `[2,"Bread, rye"]` + "\n",
+		},
+		{
+			name:   "ndjson empty",
+			format: "ndjson",
+			want:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, ok := Lookup(tt.format)
+			if !ok {
+				t.Fatalf("format %q not found", tt.format)
+			}
+			opts := Options{NoHeader: tt.noHeader}
+			if tt.selected != nil || tt.headers != nil {
+				opts.Columns = mustColumns(t, tt.selected, tt.headers)
+			}
+
+			got := string(writeAll(t, format, opts, tt.products))
+			if got != tt.want {
+				t.Errorf("output mismatch\ngot:  %q\nwant: %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestXLSXWriter(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     func(*testing.T) Options
+		wantRows [][]string
+	}{
+		{
+			name: "all fields",
+			opts: func(*testing.T) Options { return Options{} },
+			wantRows: [][]string{
+				{"id", "name", "category", "price", "create_date"},
+				{"1", "Milk", "dairy", "1.5", "2024-01-02"},
+				{"2", "Bread, rye", "bakery", "2", "2024-01-03"},
+			},
+		},
+		{
+			name: "fields and rename",
+			opts: func(t *testing.T) Options {
+				return Options{Columns: mustColumns(t, []string{"name", "price"}, map[string]string{"name": "product"})}
+			},
+			wantRows: [][]string{
+				{"product", "price"},
+				{"Milk", "1.5"},
+				{"Bread, rye", "2"},
+			},
+		},
+		{
+			name: "without header",
+			opts: func(t *testing.T) Options {
+				return Options{Columns: mustColumns(t, []string{"name"}, nil), NoHeader: true}
+			},
+			wantRows: [][]string{{"Milk"}, {"Bread, rye"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, _ := Lookup("xlsx")
+			data := writeAll(t, format, tt.opts(t), testProducts)
+
+			file, err := excelize.OpenReader(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("open workbook: %v", err)
+			}
+			defer file.Close()
+
+			rows, err := file.GetRows(sheetName)
+			if err != nil {
+				t.Fatalf("read rows: %v", err)
+			}
+			if len(rows) != len(tt.wantRows) {
+				t.Fatalf("got %d rows, want %d: %v", len(rows), len(tt.wantRows), rows)
+			}
+			for i := range rows {
+				if len(rows[i]) != len(tt.wantRows[i]) {
+					t.Fatalf("row %d: got %v, want %v", i, rows[i], tt.wantRows[i])
+				}
+				for j := range rows[i] {
+					if rows[i][j] != tt.wantRows[i][j] {
+						t.Errorf("row %d: got %v, want %v", i, rows[i], tt.wantRows[i])
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestParquetWriter(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        func(*testing.T) Options
+		wantColumns []string
+	}{
+		{
+			name:        "all fields",
+			opts:        func(*testing.T) Options { return Options{} },
+			wantColumns: []string{"id", "name", "category", "price", "create_date"},
+		},
+		{
+			name: "fields and rename",
+			opts: func(t *testing.T) Options {
+				return Options{Columns: mustColumns(t, []string{"price", "id"}, map[string]string{"price": "cost"})}
+			},
+			wantColumns: []string{"cost", "id"},
+		},
+		{
+			name: "header option ignored",
+			opts: func(t *testing.T) Options {
+				return Options{Columns: mustColumns(t, []string{"name"}, nil), NoHeader: true}
+			},
+			wantColumns: []string{"name"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, _ := Lookup("parquet")
+			data := writeAll(t, format, tt.opts(t), testProducts)
+
+			file, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				t.Fatalf("open parquet file: %v", err)
+			}
+			if got := file.NumRows(); got != int64(len(testProducts)) {
+				t.Errorf("got %d rows, want %d", got, len(testProducts))
+			}
+
+			schemaFields := file.Schema().Fields()
+			if len(schemaFields) != len(tt.wantColumns) {
+				t.Fatalf("got %d columns, want %v", len(schemaFields), tt.wantColumns)
+			}
+			for i, field := range schemaFields {
+				if field.Name() != tt.wantColumns[i] {
+					t.Errorf("column %d: got %q, want %q", i, field.Name(), tt.wantColumns[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNewColumns(t *testing.T) {
+	tests := []struct {
+		name     string
+		selected []string
+		headers  map[string]string
+		want     []Column
+		wantErr  bool
+	}{
+		{
+			name: "defaults",
+			want: []Column{
+				{"id", "id"}, {"name", "name"}, {"category", "category"},
+				{"price", "price"}, {"create_date", "create_date"},
+			},
+		},
+		{
+			name:     "selected order",
+			selected: []string{"price", "id"},
+			want:     []Column{{"price", "price"}, {"id", "id"}},
+		},
+		{
+			name:     "rename",
+			selected: []string{"name"},
+			headers:  map[string]string{"name": "product"},
+			want:     []Column{{"name", "product"}},
+		},
+		{
+			name:     "unknown field",
+			selected: []string{"weight"},
+			wantErr:  true,
+		},
+		{
+			name:     "duplicate field",
+			selected: []string{"id", "id"},
+			wantErr:  true,
+		},
+		{
+			name:     "rename clashes with another column",
+			selected: []string{"id", "name"},
+			headers:  map[string]string{"name": "id"},
+			wantErr:  true,
+		},
+		{
+			name:    "rename of unknown field",
+			headers: map[string]string{"weight": "kg"},
+			wantErr: true,
+		},
+		{
+			name:    "empty header",
+			headers: map[string]string{"id": ""},
+			wantErr: true,
+		},
+		{
+			name:    "header with separator",
+			headers: map[string]string{"id": "a,b"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewColumns(tt.selected, tt.headers)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", "csv"},
+		{"*/*", "csv"},
+		{"application/json", "json"},
+		{"text/html, application/x-ndjson;q=0.9", "ndjson"},
+		{"application/json;q=0, application/vnd.apache.parquet", "parquet"},
+		{"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "xlsx"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			if got := Negotiate(tt.accept).Name; got != tt.want {
+				t.Errorf("Negotiate(%q) = %s, want %s", tt.accept, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for _, name := range []string{"csv", "JSON", "ndjson", "xlsx", "parquet"} {
+		if _, ok := Lookup(name); !ok {
+			t.Errorf("Lookup(%q) found nothing", name)
+		}
+	}
+	if _, ok := Lookup("xml"); ok {
+		t.Error("Lookup(\"xml\") found a format")
+	}
+}
@@ -0,0 +1,110 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+)
+
+// encodeRow encodes a product as a JSON object keyed by column headers,
+// or as an array of values when the header is omitted.
+func encodeRow(buf *bytes.Buffer, columns []Column, noHeader bool, product models.Product) error {
+	open, end := byte('{'), byte('}')
+	if noHeader {
+		open, end = '[', ']'
+	}
+
+	buf.WriteByte(open)
+	for i, column := range columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if !noHeader {
+			key, err := json.Marshal(column.Header)
+			if err != nil {
+				return err
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+		}
+		value, err := json.Marshal(column.value(product))
+		if err != nil {
+			return err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte(end)
+	return nil
+}
+
+// JSONWriter writes products as a single JSON array.
+type JSONWriter struct {
+	w        io.Writer
+	columns  []Column
+	noHeader bool
+	buf      bytes.Buffer
+	count    int
+}
+
+// NewJSONWriter creates a new JSONWriter.
+func NewJSONWriter(w io.Writer, opts Options) (Writer, error) {
+	return &JSONWriter{w: w, columns: opts.columns(), noHeader: opts.NoHeader}, nil
+}
+
+// Write appends a product to the array.
+func (j *JSONWriter) Write(product models.Product) error {
+	j.buf.Reset()
+	if j.count == 0 {
+		j.buf.WriteByte('[')
+	} else {
+		j.buf.WriteByte(',')
+	}
+	j.count++
+
+	if err := encodeRow(&j.buf, j.columns, j.noHeader, product); err != nil {
+		return err
+	}
+	_, err := j.w.Write(j.buf.Bytes())
+	return err
+}
+
+// Close terminates the array.
+func (j *JSONWriter) Close() error {
+	closing := "]\n"
+	if j.count == 0 {
+		closing = "[]\n"
+	}
+	_, err := io.WriteString(j.w, closing)
+	return err
+}
+
+// NDJSONWriter writes products as newline-delimited JSON values.
+type NDJSONWriter struct {
+	w        io.Writer
+	columns  []Column
+	noHeader bool
+	buf      bytes.Buffer
+}
+
+// NewNDJSONWriter creates a new NDJSONWriter.
+func NewNDJSONWriter(w io.Writer, opts Options) (Writer, error) {
+	return &NDJSONWriter{w: w, columns: opts.columns(), noHeader: opts.NoHeader}, nil
+}
+
+// Write writes a product on its own line.
+func (n *NDJSONWriter) Write(product models.Product) error {
+	n.buf.Reset()
+	if err := encodeRow(&n.buf, n.columns, n.noHeader, product); err != nil {
+		return err
+	}
+	n.buf.WriteByte('\n')
+	_, err := n.w.Write(n.buf.Bytes())
+	return err
+}
+
+// Close does nothing, every line is written as soon as it is encoded.
+func (n *NDJSONWriter) Close() error {
+	return nil
+}
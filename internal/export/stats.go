@@ -0,0 +1,45 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+)
+
+// categoryStatsHeader is the header row of a category statistics CSV file.
+var categoryStatsHeader = []string{"category", "count", "min", "max", "mean", "median", "p90", "p95", "stddev", "sum"}
+
+// WriteCategoryStatsCSV writes per-category statistics as CSV rows preceded by a header.
+func WriteCategoryStatsCSV(w io.Writer, stats []models.CategoryStats) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(categoryStatsHeader); err != nil {
+		return err
+	}
+
+	for _, s := range stats {
+		record := []string{
+			s.Category,
+			strconv.FormatInt(s.Count, 10),
+			formatAmount(s.Min),
+			formatAmount(s.Max),
+			formatAmount(s.Mean),
+			formatAmount(s.Median),
+			formatAmount(s.P90),
+			formatAmount(s.P95),
+			formatAmount(s.StdDev),
+			formatAmount(s.Sum),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
@@ -0,0 +1,71 @@
+package export
+
+import (
+	"io"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"github.com/xuri/excelize/v2"
+)
+
+const sheetName = "Sheet1"
+
+// XLSXWriter writes products to a single-sheet Excel workbook.
+// Rows are spooled by the excelize stream writer and the workbook is written out on Close.
+type XLSXWriter struct {
+	w       io.Writer
+	file    *excelize.File
+	stream  *excelize.StreamWriter
+	columns []Column
+	row     int
+}
+
+// NewXLSXWriter creates a new XLSXWriter with the header row already in place.
+func NewXLSXWriter(w io.Writer, opts Options) (Writer, error) {
+	file := excelize.NewFile()
+	stream, err := file.NewStreamWriter(sheetName)
+	if err != nil {
+		return nil, err
+	}
+
+	x := &XLSXWriter{w: w, file: file, stream: stream, columns: opts.columns()}
+	if opts.NoHeader {
+		return x, nil
+	}
+
+	values := make([]interface{}, len(x.columns))
+	for i, column := range x.columns {
+		values[i] = column.Header
+	}
+	if err := x.setRow(values); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// Write adds a product as a new row.
+func (x *XLSXWriter) Write(product models.Product) error {
+	values := make([]interface{}, len(x.columns))
+	for i, column := range x.columns {
+		values[i] = column.value(product)
+	}
+	return x.setRow(values)
+}
+
+// Close finalizes the workbook and writes it to the underlying writer.
+func (x *XLSXWriter) Close() error {
+	defer x.file.Close()
+
+	if err := x.stream.Flush(); err != nil {
+		return err
+	}
+	return x.file.Write(x.w)
+}
+
+func (x *XLSXWriter) setRow(values []interface{}) error {
+	x.row++
+	cell, err := excelize.CoordinatesToCellName(1, x.row)
+	if err != nil {
+		return err
+	}
+	return x.stream.SetRow(cell, values)
+}
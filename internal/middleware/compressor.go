@@ -1,12 +1,14 @@
 package middleware
 
 import (
-	"bytes"
+	"compress/gzip"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
 
 	"github.com/drstein77/priceanalyzer/internal/compress"
+	"github.com/drstein77/priceanalyzer/internal/export"
 	"go.uber.org/zap"
 )
 
@@ -83,77 +85,222 @@ func CreateCompressMiddleware(archiveType string) func(http.Handler) http.Handle
 	}
 }
 
-// CompressResponseMiddleware creates middleware to compress responses into a ZIP archive.
+// CompressResponseMiddleware creates middleware to package responses into an archive.
+// The export format is taken from the format parameter or the Accept header and
+// passed to the handler through the request context. The container is chosen by
+// the type parameter: zip (default), tar, tar.gz or none for a bare file, which is
+// gzip-encoded when the client accepts it.
+//
+// The handler output is packaged as it is written, so the archive is streamed to
+// the client instead of being assembled in memory. A TAR entry needs its size up
+// front, so tar and tar.gz archives hold the data in numbered parts of about 1MB
+// when it does not fit in one. Error responses sent before the first byte of data
+// are passed through unpackaged.
 func CompressResponseMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Create a buffer to capture the response
-		var buf bytes.Buffer
-		// Use a ResponseWriter that writes to the buffer
-		rr := &responseRecorder{
-			ResponseWriter: w,
-			body:           &buf,
-		}
-
-		// Call the next handler with the custom ResponseRecorder
-		next.ServeHTTP(rr, r)
-
-		// Get the status code and headers
-		statusCode := rr.statusCode
-		if statusCode == 0 {
-			statusCode = http.StatusOK
-		}
-
-		// Package the data into a ZIP archive
-		var archiveBuffer bytes.Buffer
-		zw, err := compress.NewZipWriter(&archiveBuffer, "data.csv")
+		format, err := exportFormat(r)
 		if err != nil {
-			zap.L().Error("Failed to create ZIP writer", zap.Error(err))
-			http.Error(w, "Error creating ZIP archive", http.StatusInternalServerError)
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		r = r.WithContext(export.NewContext(r.Context(), format))
 
-		// Write data into the archive
-		_, err = zw.Write(buf.Bytes())
+		archiveType := r.URL.Query().Get("type")
+		if archiveType == "" {
+			archiveType = "zip"
+		}
+		container, err := responseContainerFor(archiveType, format, r)
 		if err != nil {
-			zap.L().Error("Failed to write to ZIP archive", zap.Error(err))
-			http.Error(w, "Error packing data into ZIP archive", http.StatusInternalServerError)
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		// Close the archive
-		if err := zw.Close(); err != nil {
-			zap.L().Error("Failed to close ZIP archive", zap.Error(err))
-			http.Error(w, "Error finalizing ZIP archive", http.StatusInternalServerError)
-			return
+		aw := &archiveResponseWriter{
+			ResponseWriter: w,
+			container:      container,
+			fileName:       format.FileName("data"),
 		}
 
-		// Set headers
-		w.Header().Set("Content-Type", "application/zip")
-		w.Header().Set("Content-Disposition", `attachment; filename="data.zip"`)
-		w.WriteHeader(statusCode)
+		// Call the next handler with the archiving ResponseWriter
+		next.ServeHTTP(aw, r)
 
-		// Send the archive to the client
-		_, err = w.Write(archiveBuffer.Bytes())
-		if err != nil {
-			zap.L().Error("Failed to write ZIP archive to response", zap.Error(err))
+		// Finalize the archive
+		if err := aw.Close(); err != nil {
+			zap.L().Error("Failed to finalize archive", zap.Error(err))
 		}
 	})
 }
 
-// responseRecorder captures the response for later packaging into an archive.
-type responseRecorder struct {
+// responseContainer describes how an export file is packaged for download.
+type responseContainer struct {
+	contentType string
+	fileName    string
+	encoding    string
+	vary        string
+	newWriter   func(w io.Writer, fileName string) (io.WriteCloser, error)
+}
+
+// responseContainerFor returns the container for the requested archive type.
+func responseContainerFor(archiveType string, format export.Format, r *http.Request) (responseContainer, error) {
+	switch archiveType {
+	case "zip":
+		return responseContainer{
+			contentType: "application/zip",
+			fileName:    "data.zip",
+			newWriter: func(w io.Writer, fileName string) (io.WriteCloser, error) {
+				return compress.NewZipWriter(w, fileName)
+			},
+		}, nil
+	case "tar":
+		return responseContainer{
+			contentType: "application/x-tar",
+			fileName:    "data.tar",
+			newWriter: func(w io.Writer, fileName string) (io.WriteCloser, error) {
+				return compress.NewTarWriter(w, fileName)
+			},
+		}, nil
+	case "tar.gz":
+		return responseContainer{
+			contentType: "application/gzip",
+			fileName:    "data.tar.gz",
+			newWriter: func(w io.Writer, fileName string) (io.WriteCloser, error) {
+				return compress.NewTarGzWriter(w, fileName)
+			},
+		}, nil
+	case "none":
+		container := responseContainer{
+			contentType: format.ContentType,
+			fileName:    format.FileName("data"),
+			vary:        "Accept-Encoding",
+			newWriter: func(w io.Writer, _ string) (io.WriteCloser, error) {
+				return nopWriteCloser{w}, nil
+			},
+		}
+		if acceptsGzip(r) {
+			container.encoding = "gzip"
+			container.newWriter = func(w io.Writer, _ string) (io.WriteCloser, error) {
+				return gzip.NewWriter(w), nil
+			}
+		}
+		return container, nil
+	default:
+		return responseContainer{}, fmt.Errorf("unsupported archive type %q", archiveType)
+	}
+}
+
+// acceptsGzip reports whether the client accepts gzip content encoding.
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.EqualFold(strings.TrimSpace(coding), "gzip") && strings.ReplaceAll(params, " ", "") != "q=0" {
+			return true
+		}
+	}
+	return false
+}
+
+// nopWriteCloser adds a no-op Close method to an io.Writer.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// Close does nothing.
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// exportFormat determines the requested export format.
+// An explicit format parameter takes precedence over the Accept header.
+func exportFormat(r *http.Request) (export.Format, error) {
+	name := r.URL.Query().Get("format")
+	if name == "" {
+		return export.Negotiate(r.Header.Get("Accept")), nil
+	}
+
+	format, ok := export.Lookup(name)
+	if !ok {
+		return export.Format{}, fmt.Errorf("unsupported export format %q", name)
+	}
+	return format, nil
+}
+
+// archiveResponseWriter packages the response body into an archive on the fly.
+type archiveResponseWriter struct {
 	http.ResponseWriter
-	statusCode int
-	body       *bytes.Buffer
+	container   responseContainer
+	fileName    string
+	archive     io.WriteCloser
+	wroteHeader bool
+	passthrough bool
+}
+
+// WriteHeader sends the archive headers, or the handler's own headers for an error status.
+func (aw *archiveResponseWriter) WriteHeader(code int) {
+	if aw.wroteHeader {
+		return
+	}
+	aw.wroteHeader = true
+
+	if code >= http.StatusBadRequest {
+		aw.passthrough = true
+		aw.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	header := aw.Header()
+	header.Set("Content-Type", aw.container.contentType)
+	header.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, aw.container.fileName))
+	header.Del("Content-Length")
+	if aw.container.encoding != "" {
+		header.Set("Content-Encoding", aw.container.encoding)
+	}
+	if aw.container.vary != "" {
+		header.Add("Vary", aw.container.vary)
+	}
+	aw.ResponseWriter.WriteHeader(code)
 }
 
-// WriteHeader records the status code.
-func (rr *responseRecorder) WriteHeader(code int) {
-	rr.statusCode = code
-	// Do not write immediately, wait until data is packed
+// Write packs data into the archive, opening it on the first call.
+func (aw *archiveResponseWriter) Write(b []byte) (int, error) {
+	if !aw.wroteHeader {
+		aw.WriteHeader(http.StatusOK)
+	}
+	if aw.passthrough {
+		return aw.ResponseWriter.Write(b)
+	}
+	if err := aw.open(); err != nil {
+		return 0, err
+	}
+	return aw.archive.Write(b)
 }
 
-// Write writes data to the buffer.
-func (rr *responseRecorder) Write(b []byte) (int, error) {
-	return rr.body.Write(b)
+// Close finalizes the archive; a handler that wrote nothing gets an empty one.
+func (aw *archiveResponseWriter) Close() error {
+	if !aw.wroteHeader {
+		aw.WriteHeader(http.StatusOK)
+	}
+	if aw.passthrough {
+		return nil
+	}
+	if err := aw.open(); err != nil {
+		return err
+	}
+	return aw.archive.Close()
+}
+
+// Unwrap returns the original ResponseWriter for http.ResponseController.
+func (aw *archiveResponseWriter) Unwrap() http.ResponseWriter {
+	return aw.ResponseWriter
+}
+
+func (aw *archiveResponseWriter) open() error {
+	if aw.archive != nil {
+		return nil
+	}
+	archive, err := aw.container.newWriter(aw.ResponseWriter, aw.fileName)
+	if err != nil {
+		return err
+	}
+	aw.archive = archive
+	return nil
 }
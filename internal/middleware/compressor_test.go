@@ -0,0 +1,272 @@
+package middleware
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drstein77/priceanalyzer/internal/export"
+)
+
+const testCSV = "id,name\n1,Milk\n"
+
+// exportHandler writes testCSV, or an error when the status is set.
+func exportHandler(status int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status != 0 {
+			http.Error(w, "failed", status)
+			return
+		}
+		io.WriteString(w, testCSV)
+	})
+}
+
+func unpackZip(t *testing.T, body []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", f.Name, err)
+		}
+		files[f.Name] = string(content)
+	}
+	return files
+}
+
+func unpackTar(t *testing.T, r io.Reader) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatalf("read tar: %v", err)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("read %s: %v", header.Name, err)
+		}
+		files[header.Name] = string(content)
+	}
+}
+
+func gunzip(t *testing.T, body []byte) []byte {
+	t.Helper()
+	gr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("open gzip: %v", err)
+	}
+	content, err := io.ReadAll(gr)
+	if err != nil {
+		t.Fatalf("read gzip: %v", err)
+	}
+	return content
+}
+
+func TestCompressResponseMiddleware(t *testing.T) {
+	tests := []struct {
+		name            string
+		query           string
+		acceptEncoding  string
+		wantContentType string
+		wantFileName    string
+		wantEncoding    string
+		unpack          func(*testing.T, []byte) map[string]string
+	}{
+		{
+			name:            "default zip",
+			wantContentType: "application/zip",
+			wantFileName:    "data.zip",
+			unpack:          unpackZip,
+		},
+		{
+			name:            "zip",
+			query:           "type=zip",
+			wantContentType: "application/zip",
+			wantFileName:    "data.zip",
+			unpack:          unpackZip,
+		},
+		{
+			name:            "tar",
+			query:           "type=tar",
+			wantContentType: "application/x-tar",
+			wantFileName:    "data.tar",
+			unpack: func(t *testing.T, body []byte) map[string]string {
+				return unpackTar(t, bytes.NewReader(body))
+			},
+		},
+		{
+			name:            "tar.gz",
+			query:           "type=tar.gz",
+			wantContentType: "application/gzip",
+			wantFileName:    "data.tar.gz",
+			unpack: func(t *testing.T, body []byte) map[string]string {
+				return unpackTar(t, bytes.NewReader(gunzip(t, body)))
+			},
+		},
+		{
+			name:            "none",
+			query:           "type=none",
+			wantContentType: "text/csv",
+			wantFileName:    "data.csv",
+			unpack: func(_ *testing.T, body []byte) map[string]string {
+				return map[string]string{"data.csv": string(body)}
+			},
+		},
+		{
+			name:            "none with gzip",
+			query:           "type=none",
+			acceptEncoding:  "br, gzip;q=0.8",
+			wantContentType: "text/csv",
+			wantFileName:    "data.csv",
+			wantEncoding:    "gzip",
+			unpack: func(t *testing.T, body []byte) map[string]string {
+				return map[string]string{"data.csv": string(gunzip(t, body))}
+			},
+		},
+		{
+			name:            "none with gzip refused",
+			query:           "type=none",
+			acceptEncoding:  "gzip;q=0",
+			wantContentType: "text/csv",
+			wantFileName:    "data.csv",
+			unpack: func(_ *testing.T, body []byte) map[string]string {
+				return map[string]string{"data.csv": string(body)}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v0/prices?"+tt.query, nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+
+			CompressResponseMiddleware(exportHandler(0)).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("got status %d: %s", rec.Code, rec.Body)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			wantDisposition := `attachment; filename="` + tt.wantFileName + `"`
+			if got := rec.Header().Get("Content-Disposition"); got != wantDisposition {
+				t.Errorf("Content-Disposition = %q, want %q", got, wantDisposition)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+
+			files := tt.unpack(t, rec.Body.Bytes())
+			if len(files) != 1 || files["data.csv"] != testCSV {
+				t.Errorf("unexpected content: %q", files)
+			}
+		})
+	}
+}
+
+func TestCompressResponseMiddlewareFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		accept   string
+		wantFile string
+	}{
+		{name: "default", wantFile: "data.csv"},
+		{name: "parameter", query: "format=ndjson", wantFile: "data.ndjson"},
+		{name: "accept header", accept: "application/json", wantFile: "data.json"},
+		{name: "parameter over accept header", query: "format=xlsx", accept: "application/json", wantFile: "data.xlsx"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var format export.Format
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				format = export.FromContext(r.Context())
+				io.WriteString(w, testCSV)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v0/prices?"+tt.query, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+
+			CompressResponseMiddleware(handler).ServeHTTP(rec, req)
+
+			if got := format.FileName("data"); got != tt.wantFile {
+				t.Errorf("handler got format for %q, want %q", got, tt.wantFile)
+			}
+			if _, ok := unpackZip(t, rec.Body.Bytes())[tt.wantFile]; !ok {
+				t.Errorf("archive does not contain %s", tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestCompressResponseMiddlewareErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		status     int
+		wantStatus int
+	}{
+		{name: "unsupported type", query: "type=rar", wantStatus: http.StatusBadRequest},
+		{name: "unsupported format", query: "format=xml", wantStatus: http.StatusBadRequest},
+		{name: "handler error", status: http.StatusInternalServerError, wantStatus: http.StatusInternalServerError},
+		{name: "handler error with tar", query: "type=tar.gz", status: http.StatusBadRequest, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v0/prices?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			CompressResponseMiddleware(exportHandler(tt.status)).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Content-Disposition"); got != "" {
+				t.Errorf("error response has Content-Disposition %q", got)
+			}
+			if got := rec.Header().Get("Content-Type"); got != "text/plain; charset=utf-8" {
+				t.Errorf("error response has Content-Type %q", got)
+			}
+		})
+	}
+}
+
+func TestCompressResponseMiddlewareEmpty(t *testing.T) {
+	handler := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	req := httptest.NewRequest(http.MethodGet, "/api/v0/prices?type=tar", nil)
+	rec := httptest.NewRecorder()
+
+	CompressResponseMiddleware(handler).ServeHTTP(rec, req)
+
+	files := unpackTar(t, bytes.NewReader(rec.Body.Bytes()))
+	if content, ok := files["data.csv"]; !ok || content != "" {
+		t.Errorf("got %q, want an empty data.csv", files)
+	}
+}
@@ -1,17 +1,474 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type ProcessResponse struct {
-	TotalItems      int     `json:"total_items"`
-	TotalCategories int     `json:"total_categories"`
-	TotalPrice      float64 `json:"total_price"`
+	UploadID        int            `json:"upload_id,omitempty"`
+	TotalItems      int            `json:"total_items"`
+	TotalCategories int            `json:"total_categories"`
+	TotalPrice      float64        `json:"total_price"`
+	Upload          *UploadStats   `json:"upload,omitempty"`
+	Outliers        []PriceOutlier `json:"outliers,omitempty"`
+	Alerts          []AlertMatch   `json:"alerts,omitempty"`
+}
+
+// UploadStats describes what a single upload contributed.
+type UploadStats struct {
+	ID                int        `json:"id"`
+	RowsInserted      int        `json:"rows_inserted"`
+	CategoriesTouched int        `json:"categories_touched"`
+	TotalPrice        float64    `json:"total_price"`
+	FirstDate         *time.Time `json:"first_date"`
+	LastDate          *time.Time `json:"last_date"`
 }
 
 type Product struct {
+	ID        int        `json:"id"`
+	ProductID int        `json:"product_id,omitempty"`
+	Name      string     `json:"name"`
+	Category  string     `json:"category"`
+	Price     float64    `json:"price"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// ProductFilter narrows a selection of products by date, price and category.
+// Nil bounds and an empty category are not applied. Deleted products are
+// left out unless IncludeDeleted is set. AsOf selects the state of every
+// product at that moment instead of all stored rows.
+type ProductFilter struct {
+	Start          *time.Time
+	End            *time.Time
+	Min            *float64
+	Max            *float64
+	Category       string
+	ProductID      *int
+	IncludeDeleted bool
+	AsOf           *time.Time
+}
+
+// Cursor points at the last row of a page in keyset order.
+type Cursor struct {
+	Sort string `json:"s"`
+	Desc bool   `json:"d,omitempty"`
+	Key  string `json:"k"`
+	ID   int    `json:"i"`
+}
+
+// ListQuery describes a single page of a product listing.
+type ListQuery struct {
+	Filter ProductFilter
+	Sort   string
+	Desc   bool
+	Limit  int
+	Cursor string
+	After  *Cursor
+}
+
+// ProductPage is a page of products with the token for the next one.
+type ProductPage struct {
+	Items []Product `json:"items"`
+	Next  string    `json:"next,omitempty"`
+}
+
+// ProductInput is a product as submitted in a request body.
+// Absent fields are nil; the date uses the upload format YYYY-MM-DD.
+type ProductInput struct {
+	Name       *string      `json:"name"`
+	Category   *string      `json:"category"`
+	Price      *json.Number `json:"price"`
+	CreateDate *string      `json:"create_date"`
+}
+
+// ProductPatch holds the validated fields of a partial product update.
+// Nil fields are left unchanged.
+type ProductPatch struct {
+	Name      *string
+	Category  *string
+	Price     *float64
+	CreatedAt *time.Time
+}
+
+// BulkUpdate describes a change applied to every product matching a filter.
+type BulkUpdate struct {
+	Category           *string  `json:"category"`
+	PriceChangePercent *float64 `json:"price_change_percent"`
+}
+
+// BulkResult reports the outcome of a bulk operation.
+type BulkResult struct {
+	Affected int64 `json:"affected"`
+}
+
+// ProductRef identifies a product across uploads by its name and category.
+type ProductRef struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Category string `json:"category"`
+}
+
+// PricePeriod is an interval during which a product kept the same price.
+// EffectiveTo is nil for the current price.
+type PricePeriod struct {
+	Price         float64    `json:"price"`
+	EffectiveFrom time.Time  `json:"effective_from"`
+	EffectiveTo   *time.Time `json:"effective_to"`
+}
+
+// PriceHistory is the time series of price changes of a product.
+type PriceHistory struct {
+	Product ProductRef    `json:"product"`
+	Periods []PricePeriod `json:"periods"`
+}
+
+// Category is a canonical product category. Aliases are alternative
+// lower-case spellings that resolve to it during ingestion.
+type Category struct {
+	ID       int      `json:"id"`
+	Name     string   `json:"name"`
+	ParentID *int     `json:"parent_id"`
+	Aliases  []string `json:"aliases"`
+}
+
+// CategoryStats summarizes the prices of a single category.
+type CategoryStats struct {
+	Category string  `json:"category"`
+	Count    int64   `json:"count"`
+	Min      float64 `json:"min"`
+	Max      float64 `json:"max"`
+	Mean     float64 `json:"mean"`
+	Median   float64 `json:"median"`
+	P90      float64 `json:"p90"`
+	P95      float64 `json:"p95"`
+	StdDev   float64 `json:"stddev"`
+	Sum      float64 `json:"sum"`
+}
+
+// TimeSeriesQuery selects the products to aggregate and how to bucket them.
+type TimeSeriesQuery struct {
+	Filter   ProductFilter
+	Interval string
+	TimeZone string
+	// Limit stops the series at the bucket Limit intervals after the first one
+	Limit int
+}
+
+// TimeBucket aggregates the prices of a single period.
+// The price fields are empty for periods without prices.
+type TimeBucket struct {
+	Start time.Time `json:"start"`
+	Count int64     `json:"count"`
+	Avg   *float64  `json:"avg"`
+	Min   *float64  `json:"min"`
+	Max   *float64  `json:"max"`
+}
+
+// TimeSeries is a sequence of consecutive periods.
+type TimeSeries struct {
+	Interval string       `json:"interval"`
+	TimeZone string       `json:"time_zone"`
+	Buckets  []TimeBucket `json:"buckets"`
+}
+
+// Kinds of price changes between two snapshots.
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeRepriced = "repriced"
+)
+
+// DiffSide identifies a snapshot of prices: the rows of an upload,
+// or the prices effective at a moment.
+type DiffSide struct {
+	UploadID *int
+	AsOf     *time.Time
+}
+
+// DiffQuery selects two snapshots to compare and how to order the changes.
+type DiffQuery struct {
+	From DiffSide
+	To   DiffSide
+	Sort string
+	Desc bool
+}
+
+// PriceChange describes how the price of a product differs between two snapshots.
+// Added products have no old price and removed products no new price.
+type PriceChange struct {
+	Product       ProductRef `json:"product"`
+	Status        string     `json:"status"`
+	OldPrice      *float64   `json:"old_price"`
+	NewPrice      *float64   `json:"new_price"`
+	Change        *float64   `json:"change"`
+	ChangePercent *float64   `json:"change_percent"`
+}
+
+// PriceDiff lists the changes between two snapshots.
+type PriceDiff struct {
+	Added    int           `json:"added"`
+	Removed  int           `json:"removed"`
+	Repriced int           `json:"repriced"`
+	Changes  []PriceChange `json:"changes"`
+}
+
+// Outlier detection methods.
+const (
+	OutlierZScore  = "zscore"
+	OutlierIQR     = "iqr"
+	OutlierHistory = "history"
+)
+
+// OutlierQuery selects the products to examine and the methods and thresholds to apply.
+type OutlierQuery struct {
+	Filter    ProductFilter
+	Methods   []string
+	ZScore    float64
+	IQRFactor float64
+	Deviation float64
+	Limit     int
+}
+
+// Outlier is a product whose price stands out by one of the detection methods.
+// Score is the z-score, the distance from the interquartile range in IQRs or the
+// percent deviation from the product's median price, depending on the method;
+// Expected is the category mean, the category median or the product's median price.
+type Outlier struct {
+	Product  Product `json:"product"`
+	Method   string  `json:"method"`
+	Score    float64 `json:"score"`
+	Expected float64 `json:"expected"`
+}
+
+// PriceOutlier is an uploaded row whose price deviates from the product's current price
+// by more than the configured threshold. Rejected rows are not stored.
+type PriceOutlier struct {
+	Row              int     `json:"row"`
+	Name             string  `json:"name"`
+	Category         string  `json:"category"`
+	Price            float64 `json:"price"`
+	CurrentPrice     float64 `json:"current_price"`
+	DeviationPercent float64 `json:"deviation_percent"`
+	Rejected         bool    `json:"rejected"`
+}
+
+// Basket is a named set of products with weights, tracked by a price index.
+type Basket struct {
+	ID    int          `json:"id"`
+	Name  string       `json:"name"`
+	Items []BasketItem `json:"items"`
+}
+
+// BasketItem is a product of a basket. Weight is the quantity of the product in the basket.
+type BasketItem struct {
+	ProductID int     `json:"product_id"`
+	Weight    float64 `json:"weight"`
+}
+
+// IndexQuery selects the basket, base period and interval of a price index.
+type IndexQuery struct {
+	Basket   string
+	Base     time.Time
+	Interval string
+}
+
+// IndexPoint is the value of a price index for a single period.
+// Change is the percent change from the previous period.
+type IndexPoint struct {
+	Period time.Time `json:"period"`
+	Index  float64   `json:"index"`
+	Change *float64  `json:"change"`
+}
+
+// PriceIndex is a Laspeyres price index of a basket relative to a base period,
+// where it equals 100. Products without a price in the base period are excluded.
+type PriceIndex struct {
+	Basket   string       `json:"basket"`
+	Base     time.Time    `json:"base"`
+	Interval string       `json:"interval"`
+	Excluded []int        `json:"excluded"`
+	Points   []IndexPoint `json:"points"`
+}
+
+// Trend directions.
+const (
+	TrendUp   = "up"
+	TrendDown = "down"
+	TrendFlat = "flat"
+)
+
+// TrendQuery selects the series to analyze and the window of the indicators.
+type TrendQuery struct {
+	Filter   ProductFilter
+	Interval string
+	TimeZone string
+	Window   int
+}
+
+// TrendPoint holds the indicators of a single period. Price is the average price of
+// the period, carried forward from the previous period when there is none. The moving
+// averages and rolling extremes are empty until the window is full.
+type TrendPoint struct {
+	Period    time.Time `json:"period"`
+	Price     *float64  `json:"price"`
+	SMA       *float64  `json:"sma"`
+	EMA       *float64  `json:"ema"`
+	Min       *float64  `json:"min"`
+	Max       *float64  `json:"max"`
+	Direction string    `json:"direction,omitempty"`
+}
+
+// Trend lists the indicators per period together with the overall direction,
+// which compares the latest moving average with the one a window earlier.
+type Trend struct {
+	Interval  string       `json:"interval"`
+	Window    int          `json:"window"`
+	Direction string       `json:"direction"`
+	Points    []TrendPoint `json:"points"`
+}
+
+// HistogramQuery selects the products to count and their price buckets: either
+// explicit edges, or a number of equal-width buckets spanning the prices, which
+// are spaced on a log scale when Log is set.
+type HistogramQuery struct {
+	Filter  ProductFilter
+	Buckets int
+	Edges   []float64
+	Log     bool
+}
+
+// HistogramBucket counts the prices from Lower up to, but not including, Upper.
+// The last bucket of a histogram spanning the prices includes its upper bound.
+type HistogramBucket struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+	Count int64   `json:"count"`
+}
+
+// Histogram is the distribution of prices over buckets. Underflow and Overflow
+// count the prices below the first and from the last edge on.
+type Histogram struct {
+	Buckets   []HistogramBucket `json:"buckets"`
+	Underflow int64             `json:"underflow"`
+	Overflow  int64             `json:"overflow"`
+}
+
+// TopQuery selects the products to rank, how many to return and whether to rank within each category.
+type TopQuery struct {
+	Filter ProductFilter
+	N      int
+	Desc   bool
+	Per    string
+}
+
+// RankedProduct is a product with its position in a ranking, starting at 1.
+type RankedProduct struct {
+	Rank    int     `json:"rank"`
+	Product Product `json:"product"`
+}
+
+// Period is a time range including both ends.
+type Period struct {
+	Start time.Time
+	End   time.Time
+}
+
+// CompareQuery selects the products to summarize and the two periods to compare.
+// The date range of the filter is replaced by the periods.
+type CompareQuery struct {
+	Filter ProductFilter
+	A      Period
+	B      Period
+}
+
+// PeriodSummary summarizes the prices of a category in a period.
+type PeriodSummary struct {
+	Count   int64    `json:"count"`
+	Total   float64  `json:"total"`
+	Average *float64 `json:"average"`
+}
+
+// CategoryComparison compares the prices of a category in two periods.
+// Changes are from period A to period B; percent changes are empty when A has nothing to compare with.
+type CategoryComparison struct {
+	Category             string        `json:"category"`
+	A                    PeriodSummary `json:"a"`
+	B                    PeriodSummary `json:"b"`
+	CountChange          int64         `json:"count_change"`
+	TotalChange          float64       `json:"total_change"`
+	TotalChangePercent   *float64      `json:"total_change_percent"`
+	AverageChange        *float64      `json:"average_change"`
+	AverageChangePercent *float64      `json:"average_change_percent"`
+}
+
+// Forecast models.
+const (
+	ForecastLinear      = "linear"
+	ForecastHoltWinters = "holt-winters"
+)
+
+// ForecastQuery selects the monthly series of a product or category, the model
+// to fit and the number of months to predict.
+type ForecastQuery struct {
+	Filter     ProductFilter
+	Model      string
+	Periods    int
+	Season     int
+	Confidence float64
+}
+
+// ForecastPoint is a predicted price with the bounds of its prediction interval.
+type ForecastPoint struct {
+	Period time.Time `json:"period"`
+	Value  float64   `json:"value"`
+	Lower  float64   `json:"lower"`
+	Upper  float64   `json:"upper"`
+}
+
+// Forecast lists the predictions of a model fitted on a monthly series.
+type Forecast struct {
+	Model        string          `json:"model"`
+	Confidence   float64         `json:"confidence"`
+	Observations int             `json:"observations"`
+	Predictions  []ForecastPoint `json:"predictions"`
+}
+
+// Alert conditions. Above and below compare the new price with the threshold;
+// rise and drop compare its percent change from the previous price.
+const (
+	AlertAbove       = "above"
+	AlertBelow       = "below"
+	AlertRisePercent = "rise_percent"
+	AlertDropPercent = "drop_percent"
+)
+
+// AlertRule watches uploaded prices of a product, a category or, with neither, all products.
+type AlertRule struct {
 	ID        int       `json:"id"`
 	Name      string    `json:"name"`
-	Category  string    `json:"category"`
-	Price     float64   `json:"price"`
+	ProductID *int      `json:"product_id"`
+	Category  *string   `json:"category"`
+	Condition string    `json:"condition"`
+	Threshold float64   `json:"threshold"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+// AlertMatch is an uploaded price that meets the condition of a rule.
+// The previous price is the latest one dated no later than the uploaded price outside the upload.
+type AlertMatch struct {
+	RuleID        int        `json:"rule_id"`
+	RuleName      string     `json:"rule_name"`
+	Product       ProductRef `json:"product"`
+	PriceID       int        `json:"price_id"`
+	Price         float64    `json:"price"`
+	PreviousPrice *float64   `json:"previous_price"`
+	ChangePercent *float64   `json:"change_percent"`
+}
+
+// AlertNotification is the webhook payload announcing the matches of an upload.
+type AlertNotification struct {
+	UploadID int          `json:"upload_id"`
+	Matches  []AlertMatch `json:"matches"`
+}
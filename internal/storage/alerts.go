@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"go.uber.org/zap"
+)
+
+// Notifier delivers alert notifications.
+type Notifier interface {
+	Notify(context.Context, any) error
+}
+
+const (
+	alertWorkers   = 4
+	alertQueueSize = 100
+)
+
+// alertQueue delivers alert notifications with a fixed number of workers.
+// Notifications arriving while the queue is full or closed are dropped.
+type alertQueue struct {
+	notifier Notifier
+	log      Log
+
+	mu      sync.Mutex
+	closed  bool
+	pending chan models.AlertNotification
+	workers sync.WaitGroup
+}
+
+// newAlertQueue starts the delivery workers, bound to ctx. It returns nil when notifier is nil.
+func newAlertQueue(ctx context.Context, notifier Notifier, log Log) *alertQueue {
+	if notifier == nil {
+		return nil
+	}
+
+	q := &alertQueue{
+		notifier: notifier,
+		log:      log,
+		pending:  make(chan models.AlertNotification, alertQueueSize),
+	}
+	q.workers.Add(alertWorkers)
+	for i := 0; i < alertWorkers; i++ {
+		go q.work(ctx)
+	}
+	return q
+}
+
+func (q *alertQueue) work(ctx context.Context) {
+	defer q.workers.Done()
+	for notification := range q.pending {
+		if err := q.notifier.Notify(ctx, notification); err != nil {
+			q.log.Error("Failed to deliver alerts", zap.Int("upload", notification.UploadID), zap.Error(err))
+		}
+	}
+}
+
+// push queues a notification without waiting for a free slot.
+func (q *alertQueue) push(notification models.AlertNotification) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		q.log.Error("Alert delivery is stopped, dropping alerts", zap.Int("upload", notification.UploadID))
+		return
+	}
+	select {
+	case q.pending <- notification:
+	default:
+		q.log.Error("Alert queue is full, dropping alerts", zap.Int("upload", notification.UploadID))
+	}
+}
+
+// close stops accepting notifications and waits until the queued ones are
+// delivered or ctx is done.
+func (q *alertQueue) close(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.pending)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops alert delivery, waiting for queued notifications until ctx is done.
+func (s *MemoryStorage) Close(ctx context.Context) error {
+	if s.alerts == nil {
+		return nil
+	}
+	return s.alerts.close(ctx)
+}
+
+// ListAlertRules retrieves all alert rules via dbKeeper.
+func (s *MemoryStorage) ListAlertRules(ctx context.Context) ([]models.AlertRule, error) {
+	return s.keeper.ListAlertRules(ctx)
+}
+
+// GetAlertRule retrieves a single alert rule via dbKeeper.
+func (s *MemoryStorage) GetAlertRule(ctx context.Context, id int) (*models.AlertRule, error) {
+	return s.keeper.GetAlertRule(ctx, id)
+}
+
+// CreateAlertRule validates a submitted alert rule and stores it.
+func (s *MemoryStorage) CreateAlertRule(ctx context.Context, rule models.AlertRule) (*models.AlertRule, error) {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidAlert)
+	}
+	if rule.Category != nil {
+		category := strings.TrimSpace(*rule.Category)
+		rule.Category = &category
+	}
+
+	switch rule.Condition {
+	case models.AlertAbove, models.AlertBelow:
+		if rule.Threshold < 0 {
+			return nil, fmt.Errorf("%w: threshold cannot be negative", ErrInvalidAlert)
+		}
+	case models.AlertRisePercent, models.AlertDropPercent:
+		if rule.Threshold <= 0 {
+			return nil, fmt.Errorf("%w: threshold must be a positive percentage", ErrInvalidAlert)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown condition %q, expected above, below, rise_percent or drop_percent",
+			ErrInvalidAlert, rule.Condition)
+	}
+
+	return s.keeper.CreateAlertRule(ctx, rule)
+}
+
+// DeleteAlertRule removes an alert rule via dbKeeper.
+func (s *MemoryStorage) DeleteAlertRule(ctx context.Context, id int) error {
+	return s.keeper.DeleteAlertRule(ctx, id)
+}
+
+// matchAlerts finds the prices of an upload that trigger alert rules and queues
+// them for delivery. The upload is already stored, so failures are logged rather
+// than returned.
+func (s *MemoryStorage) matchAlerts(ctx context.Context, uploadID int) []models.AlertMatch {
+	matches, err := s.keeper.MatchAlerts(ctx, uploadID)
+	if err != nil {
+		s.log.Error("Failed to match alert rules", zap.Int("upload", uploadID), zap.Error(err))
+		return nil
+	}
+	if len(matches) > 0 && s.alerts != nil {
+		s.alerts.push(models.AlertNotification{UploadID: uploadID, Matches: matches})
+	}
+	return matches
+}
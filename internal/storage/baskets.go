@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+)
+
+// ListBaskets retrieves all baskets via dbKeeper.
+func (s *MemoryStorage) ListBaskets(ctx context.Context) ([]models.Basket, error) {
+	return s.keeper.ListBaskets(ctx)
+}
+
+// GetBasket retrieves a single basket via dbKeeper.
+func (s *MemoryStorage) GetBasket(ctx context.Context, name string) (*models.Basket, error) {
+	return s.keeper.GetBasket(ctx, name)
+}
+
+// CreateBasket validates a submitted basket and stores it.
+func (s *MemoryStorage) CreateBasket(ctx context.Context, basket models.Basket) (*models.Basket, error) {
+	basket, err := validateBasket(basket)
+	if err != nil {
+		return nil, err
+	}
+	return s.keeper.CreateBasket(ctx, basket)
+}
+
+// UpdateBasket validates a submitted basket and stores it in place of the basket with the given name.
+func (s *MemoryStorage) UpdateBasket(ctx context.Context, name string, basket models.Basket) (*models.Basket, error) {
+	basket, err := validateBasket(basket)
+	if err != nil {
+		return nil, err
+	}
+	return s.keeper.UpdateBasket(ctx, name, basket)
+}
+
+// DeleteBasket removes a basket via dbKeeper.
+func (s *MemoryStorage) DeleteBasket(ctx context.Context, name string) error {
+	return s.keeper.DeleteBasket(ctx, name)
+}
+
+// GetPriceIndex validates the query and computes the price index via dbKeeper.
+func (s *MemoryStorage) GetPriceIndex(ctx context.Context, query models.IndexQuery) (*models.PriceIndex, error) {
+	if query.Interval == "" {
+		query.Interval = "month"
+	}
+	if !intervals[query.Interval] {
+		return nil, fmt.Errorf("%w: unsupported interval %q, expected day, week or month", ErrInvalidQuery, query.Interval)
+	}
+	if query.Base.IsZero() {
+		return nil, fmt.Errorf("%w: base period is required", ErrInvalidQuery)
+	}
+	return s.keeper.GetPriceIndex(ctx, query)
+}
+
+// validateBasket checks that a basket has a name and lists each product once with a positive weight.
+func validateBasket(basket models.Basket) (models.Basket, error) {
+	basket.Name = strings.TrimSpace(basket.Name)
+	if basket.Name == "" {
+		return basket, fmt.Errorf("%w: name is required", ErrInvalidBasket)
+	}
+	if len(basket.Items) == 0 {
+		return basket, fmt.Errorf("%w: at least one item is required", ErrInvalidBasket)
+	}
+
+	seen := make(map[int]bool, len(basket.Items))
+	for _, item := range basket.Items {
+		if item.Weight <= 0 {
+			return basket, fmt.Errorf("%w: weight of product %d must be positive", ErrInvalidBasket, item.ProductID)
+		}
+		if seen[item.ProductID] {
+			return basket, fmt.Errorf("%w: product %d is listed twice", ErrInvalidBasket, item.ProductID)
+		}
+		seen[item.ProductID] = true
+	}
+	return basket, nil
+}
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+)
+
+// DeleteProducts moves every product matching the filter to the trash.
+func (s *MemoryStorage) DeleteProducts(ctx context.Context, filter models.ProductFilter) (*models.BulkResult, error) {
+	affected, err := s.keeper.DeleteProducts(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &models.BulkResult{Affected: affected}, nil
+}
+
+// UpdateProducts applies a change to every product matching the filter.
+func (s *MemoryStorage) UpdateProducts(ctx context.Context, filter models.ProductFilter, update models.BulkUpdate) (*models.BulkResult, error) {
+	if update.Category == nil && update.PriceChangePercent == nil {
+		return nil, fmt.Errorf("%w: nothing to change", ErrInvalidUpdate)
+	}
+	if update.PriceChangePercent != nil && *update.PriceChangePercent <= -100 {
+		return nil, fmt.Errorf("%w: price change must be greater than -100%%", ErrInvalidUpdate)
+	}
+
+	if update.Category != nil {
+		category, err := s.canonicalCategory(ctx, *update.Category)
+		if err != nil {
+			return nil, err
+		}
+		update.Category = &category
+	}
+
+	affected, err := s.keeper.UpdateProducts(ctx, filter, update, s.createsCategories())
+	if err != nil {
+		return nil, err
+	}
+	return &models.BulkResult{Affected: affected}, nil
+}
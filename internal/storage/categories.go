@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+)
+
+// CategoryPolicy decides what ingestion does with a category that matches no known category.
+type CategoryPolicy string
+
+const (
+	// CreateUnknownCategories registers unknown categories as new top-level categories.
+	CreateUnknownCategories CategoryPolicy = "create"
+	// RejectUnknownCategories fails ingestion with ErrUnknownCategory.
+	RejectUnknownCategories CategoryPolicy = "reject"
+)
+
+// ListCategories retrieves all categories via dbKeeper.
+func (s *MemoryStorage) ListCategories(ctx context.Context) ([]models.Category, error) {
+	return s.keeper.ListCategories(ctx)
+}
+
+// GetCategory retrieves a single category via dbKeeper.
+func (s *MemoryStorage) GetCategory(ctx context.Context, id int) (*models.Category, error) {
+	return s.keeper.GetCategory(ctx, id)
+}
+
+// CreateCategory validates a submitted category and stores it.
+func (s *MemoryStorage) CreateCategory(ctx context.Context, category models.Category) (*models.Category, error) {
+	category, err := normalizeCategory(category)
+	if err != nil {
+		return nil, err
+	}
+	return s.keeper.CreateCategory(ctx, category)
+}
+
+// UpdateCategory validates a submitted category and stores it under the given id.
+func (s *MemoryStorage) UpdateCategory(ctx context.Context, id int, category models.Category) (*models.Category, error) {
+	category.ID = id
+	category, err := normalizeCategory(category)
+	if err != nil {
+		return nil, err
+	}
+	return s.keeper.UpdateCategory(ctx, category)
+}
+
+// DeleteCategory removes an unused category via dbKeeper.
+func (s *MemoryStorage) DeleteCategory(ctx context.Context, id int) error {
+	return s.keeper.DeleteCategory(ctx, id)
+}
+
+// canonicalCategories replaces the free-text categories of the products with
+// their canonical names, applying the category policy to unknown ones. Unknown
+// categories the policy allows keep their spelling; the keeper creates them in
+// the transaction storing the products, so a failed write leaves none behind.
+// Products without a category are left as they are.
+func (s *MemoryStorage) canonicalCategories(ctx context.Context, products []models.Product) error {
+	seen := make(map[string]bool)
+	var names []string
+	for i := range products {
+		products[i].Category = strings.TrimSpace(products[i].Category)
+		if name := products[i].Category; name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	resolved, err := s.keeper.ResolveCategories(ctx, names)
+	if err != nil {
+		return err
+	}
+
+	for i, product := range products {
+		if product.Category == "" {
+			continue
+		}
+		canonical, ok := resolved[product.Category]
+		switch {
+		case ok:
+			products[i].Category = canonical
+		case !s.createsCategories():
+			return fmt.Errorf("%w: %q", ErrUnknownCategory, product.Category)
+		}
+	}
+	return nil
+}
+
+// canonicalCategory returns the canonical name of a single free-text category.
+func (s *MemoryStorage) canonicalCategory(ctx context.Context, name string) (string, error) {
+	products := []models.Product{{Category: name}}
+	if err := s.canonicalCategories(ctx, products); err != nil {
+		return "", err
+	}
+	return products[0].Category, nil
+}
+
+// createsCategories reports whether writes create the unknown categories they name.
+func (s *MemoryStorage) createsCategories() bool {
+	return s.categoryPolicy == CreateUnknownCategories
+}
+
+// normalizeCategory trims the name and reduces the aliases to distinct
+// lower-case spellings other than the name itself.
+func normalizeCategory(category models.Category) (models.Category, error) {
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Name == "" {
+		return category, fmt.Errorf("%w: name is required", ErrInvalidCategory)
+	}
+
+	seen := map[string]bool{strings.ToLower(category.Name): true}
+	aliases := []string{}
+	for _, alias := range category.Aliases {
+		alias = strings.ToLower(strings.TrimSpace(alias))
+		if alias == "" || seen[alias] {
+			continue
+		}
+		seen[alias] = true
+		aliases = append(aliases, alias)
+	}
+	category.Aliases = aliases
+	return category, nil
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+)
+
+const defaultDiffSort = "magnitude"
+
+// diffSorts lists the keys price changes can be ordered by.
+var diffSorts = map[string]bool{
+	"magnitude": true,
+	"change":    true,
+	"percent":   true,
+	"name":      true,
+}
+
+// DiffPrices validates the query and compares the two snapshots via dbKeeper.
+func (s *MemoryStorage) DiffPrices(ctx context.Context, query models.DiffQuery) (*models.PriceDiff, error) {
+	if !validSide(query.From) || !validSide(query.To) {
+		return nil, fmt.Errorf("%w: from and to must each be an upload id or a date", ErrInvalidQuery)
+	}
+	if query.Sort == "" {
+		query.Sort = defaultDiffSort
+	}
+	if !diffSorts[query.Sort] {
+		return nil, fmt.Errorf("%w: unsupported sort %q, expected magnitude, change, percent or name", ErrInvalidQuery, query.Sort)
+	}
+
+	changes, err := s.keeper.DiffPrices(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &models.PriceDiff{Changes: changes}
+	for _, change := range changes {
+		switch change.Status {
+		case models.ChangeAdded:
+			diff.Added++
+		case models.ChangeRemoved:
+			diff.Removed++
+		default:
+			diff.Repriced++
+		}
+	}
+	return diff, nil
+}
+
+// validSide reports whether a diff side names exactly one snapshot.
+func validSide(side models.DiffSide) bool {
+	return (side.UploadID == nil) != (side.AsOf == nil)
+}
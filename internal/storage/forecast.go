@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+)
+
+const (
+	defaultForecastPeriods = 6
+	maxForecastPeriods     = 60
+	defaultSeason          = 12
+	defaultConfidence      = 0.95
+)
+
+// smoothingGrid lists the smoothing parameters tried when fitting Holt-Winters.
+var smoothingGrid = []float64{0.1, 0.3, 0.5, 0.7, 0.9}
+
+// GetForecast fits a linear trend or an additive Holt-Winters model on the monthly
+// prices of a product or category and predicts the following months.
+//
+// Months without prices carry the previous price forward. Prediction intervals
+// assume normally distributed errors.
+func (s *MemoryStorage) GetForecast(ctx context.Context, query models.ForecastQuery) (*models.Forecast, error) {
+	if query.Filter.ProductID == nil && query.Filter.Category == "" {
+		return nil, fmt.Errorf("%w: product_id or category is required", ErrInvalidQuery)
+	}
+	if query.Model == "" {
+		query.Model = models.ForecastLinear
+	}
+	if query.Model != models.ForecastLinear && query.Model != models.ForecastHoltWinters {
+		return nil, fmt.Errorf("%w: unsupported model %q, expected linear or holt-winters", ErrInvalidQuery, query.Model)
+	}
+
+	switch {
+	case query.Periods == 0:
+		query.Periods = defaultForecastPeriods
+	case query.Periods < 1 || query.Periods > maxForecastPeriods:
+		return nil, fmt.Errorf("%w: periods must be between 1 and %d", ErrInvalidQuery, maxForecastPeriods)
+	}
+	switch {
+	case query.Season == 0:
+		query.Season = defaultSeason
+	case query.Season < 2 || query.Season > maxForecastPeriods:
+		return nil, fmt.Errorf("%w: season must be between 2 and %d", ErrInvalidQuery, maxForecastPeriods)
+	}
+	switch {
+	case query.Confidence == 0:
+		query.Confidence = defaultConfidence
+	case query.Confidence <= 0 || query.Confidence >= 1:
+		return nil, fmt.Errorf("%w: confidence must be between 0 and 1", ErrInvalidQuery)
+	}
+
+	series, err := s.GetTimeSeries(ctx, models.TimeSeriesQuery{Filter: query.Filter, Interval: "month"})
+	if err != nil {
+		return nil, err
+	}
+
+	var values []float64
+	var last models.TimeBucket
+	for _, bucket := range series.Buckets {
+		switch {
+		case bucket.Avg != nil:
+			values = append(values, *bucket.Avg)
+		case len(values) > 0:
+			values = append(values, values[len(values)-1])
+		default:
+			continue
+		}
+		last = bucket
+	}
+
+	var predictions, errs []float64
+	switch query.Model {
+	case models.ForecastLinear:
+		if len(values) < 3 {
+			return nil, fmt.Errorf("%w: a linear trend needs at least 3 months of prices", ErrInvalidQuery)
+		}
+		predictions, errs = forecastLinear(values, query.Periods)
+	case models.ForecastHoltWinters:
+		if len(values) < 2*query.Season {
+			return nil, fmt.Errorf("%w: Holt-Winters needs at least %d months of prices", ErrInvalidQuery, 2*query.Season)
+		}
+		predictions, errs = forecastHoltWinters(values, query.Season, query.Periods)
+	}
+
+	z := math.Sqrt2 * math.Erfinv(query.Confidence)
+	forecast := &models.Forecast{
+		Model:        query.Model,
+		Confidence:   query.Confidence,
+		Observations: len(values),
+		Predictions:  make([]models.ForecastPoint, len(predictions)),
+	}
+	for h, value := range predictions {
+		forecast.Predictions[h] = models.ForecastPoint{
+			Period: last.Start.AddDate(0, h+1, 0),
+			Value:  *rounded(value),
+			Lower:  *rounded(value - z*errs[h]),
+			Upper:  *rounded(value + z*errs[h]),
+		}
+	}
+	return forecast, nil
+}
+
+// forecastLinear fits a least-squares line through the values and returns the
+// predictions for the next periods with the standard errors of their prediction.
+func forecastLinear(values []float64, periods int) ([]float64, []float64) {
+	n := float64(len(values))
+	meanT := (n - 1) / 2
+	meanY := mean(values)
+
+	var sxx, sxy float64
+	for t, y := range values {
+		sxx += (float64(t) - meanT) * (float64(t) - meanT)
+		sxy += (float64(t) - meanT) * (y - meanY)
+	}
+	slope := sxy / sxx
+	intercept := meanY - slope*meanT
+
+	var sse float64
+	for t, y := range values {
+		residual := y - (intercept + slope*float64(t))
+		sse += residual * residual
+	}
+	sigma := math.Sqrt(sse / (n - 2))
+
+	predictions := make([]float64, periods)
+	errs := make([]float64, periods)
+	for h := range predictions {
+		t := n + float64(h)
+		predictions[h] = intercept + slope*t
+		errs[h] = sigma * math.Sqrt(1+1/n+(t-meanT)*(t-meanT)/sxx)
+	}
+	return predictions, errs
+}
+
+// holtWinters holds a fitted additive Holt-Winters model.
+type holtWinters struct {
+	alpha, beta, gamma float64
+	level, trend       float64
+	seasonal           []float64
+	sse                float64
+	steps              int
+}
+
+// forecastHoltWinters fits an additive Holt-Winters model with the given season
+// length, choosing the smoothing parameters with the lowest one-step-ahead squared
+// error, and returns the predictions for the next periods with their standard errors.
+func forecastHoltWinters(values []float64, season, periods int) ([]float64, []float64) {
+	var best *holtWinters
+	for _, alpha := range smoothingGrid {
+		for _, beta := range smoothingGrid {
+			for _, gamma := range smoothingGrid {
+				model := fitHoltWinters(values, season, alpha, beta, gamma)
+				if best == nil || model.sse < best.sse {
+					best = model
+				}
+			}
+		}
+	}
+
+	sigma2 := best.sse / float64(best.steps)
+	predictions := make([]float64, periods)
+	errs := make([]float64, periods)
+	variance := 1.0
+	for h := 1; h <= periods; h++ {
+		index := (len(values) + h - 1) % season
+		predictions[h-1] = best.level + float64(h)*best.trend + best.seasonal[index]
+		errs[h-1] = math.Sqrt(sigma2 * variance)
+
+		// The variance of the next horizon adds the contribution of step h
+		c := best.alpha * (1 + float64(h)*best.beta)
+		if h%season == 0 {
+			c += best.gamma
+		}
+		variance += c * c
+	}
+	return predictions, errs
+}
+
+// fitHoltWinters runs the additive Holt-Winters recursions over the values,
+// initialized from the first two seasons.
+func fitHoltWinters(values []float64, season int, alpha, beta, gamma float64) *holtWinters {
+	// The mean of a season is the level at its middle
+	first, second := mean(values[:season]), mean(values[season:2*season])
+	middle := float64(season-1) / 2
+	trend := (second - first) / float64(season)
+	model := &holtWinters{
+		alpha:    alpha,
+		beta:     beta,
+		gamma:    gamma,
+		level:    first + middle*trend,
+		trend:    trend,
+		seasonal: make([]float64, season),
+	}
+	for i := range model.seasonal {
+		model.seasonal[i] = values[i] - (first + (float64(i)-middle)*trend)
+	}
+
+	for t := season; t < len(values); t++ {
+		s := model.seasonal[t%season]
+		residual := values[t] - (model.level + model.trend + s)
+		model.sse += residual * residual
+		model.steps++
+
+		level := alpha*(values[t]-s) + (1-alpha)*(model.level+model.trend)
+		model.trend = beta*(level-model.level) + (1-beta)*model.trend
+		model.seasonal[t%season] = gamma*(values[t]-level) + (1-gamma)*s
+		model.level = level
+	}
+	return model
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+)
+
+func TestForecastLinear(t *testing.T) {
+	tests := []struct {
+		name        string
+		values      []float64
+		periods     int
+		predictions []float64
+	}{
+		{
+			name:        "increasing line",
+			values:      []float64{1, 3, 5, 7, 9},
+			periods:     3,
+			predictions: []float64{11, 13, 15},
+		},
+		{
+			name:        "flat line",
+			values:      []float64{4, 4, 4},
+			periods:     2,
+			predictions: []float64{4, 4},
+		},
+		{
+			name:        "decreasing line",
+			values:      []float64{10, 8, 6, 4},
+			periods:     1,
+			predictions: []float64{2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			predictions, errs := forecastLinear(tt.values, tt.periods)
+			assertClose(t, predictions, tt.predictions)
+			assertClose(t, errs, make([]float64, tt.periods))
+		})
+	}
+}
+
+func TestForecastLinearErrorsGrowWithHorizon(t *testing.T) {
+	_, errs := forecastLinear([]float64{1, 4, 4, 7, 8, 12}, 4)
+	for h := 1; h < len(errs); h++ {
+		if errs[h] <= errs[h-1] {
+			t.Fatalf("standard error at horizon %d is %v, want more than %v", h+1, errs[h], errs[h-1])
+		}
+	}
+}
+
+func TestForecastHoltWinters(t *testing.T) {
+	tests := []struct {
+		name     string
+		seasonal []float64
+		trend    float64
+		seasons  int
+		periods  int
+	}{
+		{
+			name:     "quarterly with trend",
+			seasonal: []float64{1, -1, 2, -2},
+			trend:    1,
+			seasons:  3,
+			periods:  6,
+		},
+		{
+			name:     "half-yearly without trend",
+			seasonal: []float64{3, 0, -1, -2, 1, -1},
+			trend:    0,
+			seasons:  2,
+			periods:  6,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			season := len(tt.seasonal)
+			point := func(t int) float64 { return 10 + tt.trend*float64(t) + tt.seasonal[t%season] }
+
+			values := make([]float64, season*tt.seasons)
+			for i := range values {
+				values[i] = point(i)
+			}
+			want := make([]float64, tt.periods)
+			for h := range want {
+				want[h] = point(len(values) + h)
+			}
+
+			predictions, errs := forecastHoltWinters(values, season, tt.periods)
+			assertClose(t, predictions, want)
+			assertClose(t, errs, make([]float64, tt.periods))
+		})
+	}
+}
+
+func TestGetForecastRejectsSeason(t *testing.T) {
+	s := &MemoryStorage{}
+	for _, season := range []int{1, maxForecastPeriods + 1, 1 << 62} {
+		_, err := s.GetForecast(context.Background(), models.ForecastQuery{
+			Filter: models.ProductFilter{Category: "milk"},
+			Model:  models.ForecastHoltWinters,
+			Season: season,
+		})
+		if !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("season %d: got error %v, want %v", season, err, ErrInvalidQuery)
+		}
+	}
+}
+
+func assertClose(t *testing.T, got, want []float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d values, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-6 {
+			t.Fatalf("value %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,12 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+)
+
+// GetPriceHistory retrieves the price changes of a product via dbKeeper.
+func (s *MemoryStorage) GetPriceHistory(ctx context.Context, productID int) (*models.PriceHistory, error) {
+	return s.keeper.GetPriceHistory(ctx, productID)
+}
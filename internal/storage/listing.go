@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+)
+
+// ErrInvalidQuery indicates that listing parameters cannot be applied.
+var ErrInvalidQuery = errors.New("invalid query")
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// sortFields lists the fields a product listing can be ordered by.
+var sortFields = map[string]bool{
+	"id":          true,
+	"price":       true,
+	"name":        true,
+	"category":    true,
+	"create_date": true,
+}
+
+// ListProducts returns a page of products in keyset order.
+func (s *MemoryStorage) ListProducts(ctx context.Context, query models.ListQuery) (*models.ProductPage, error) {
+	if query.Sort == "" {
+		query.Sort = "id"
+	}
+	if !sortFields[query.Sort] {
+		return nil, fmt.Errorf("%w: unknown sort field %q", ErrInvalidQuery, query.Sort)
+	}
+
+	switch {
+	case query.Limit < 0:
+		return nil, fmt.Errorf("%w: limit must be positive", ErrInvalidQuery)
+	case query.Limit == 0:
+		query.Limit = defaultPageSize
+	case query.Limit > maxPageSize:
+		query.Limit = maxPageSize
+	}
+
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if cursor.Sort != query.Sort || cursor.Desc != query.Desc {
+			return nil, fmt.Errorf("%w: cursor does not match the requested order", ErrInvalidQuery)
+		}
+		query.After = cursor
+	}
+
+	// Ask for one extra row to find out whether there is a next page
+	fetch := query
+	fetch.Limit++
+	products, err := s.keeper.ListProducts(ctx, fetch)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.ProductPage{Items: products}
+	if len(products) > query.Limit {
+		page.Items = products[:query.Limit]
+		last := page.Items[len(page.Items)-1]
+		page.Next = encodeCursor(&models.Cursor{
+			Sort: query.Sort,
+			Desc: query.Desc,
+			Key:  sortKey(last, query.Sort),
+			ID:   last.ID,
+		})
+	}
+	if page.Items == nil {
+		page.Items = []models.Product{}
+	}
+
+	return page, nil
+}
+
+// sortKey returns the value of the sort field of a product in a form the database can cast back.
+func sortKey(product models.Product, sort string) string {
+	switch sort {
+	case "price":
+		return strconv.FormatFloat(product.Price, 'f', -1, 64)
+	case "name":
+		return product.Name
+	case "category":
+		return product.Category
+	case "create_date":
+		return product.CreatedAt.Format(time.RFC3339Nano)
+	default:
+		return strconv.Itoa(product.ID)
+	}
+}
+
+func encodeCursor(cursor *models.Cursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(token string) (*models.Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+
+	var cursor models.Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	return &cursor, nil
+}
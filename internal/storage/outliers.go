@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+)
+
+// Default outlier thresholds.
+const (
+	defaultZScore    = 3
+	defaultIQRFactor = 1.5
+	defaultDeviation = 50
+)
+
+// outlierMethods lists the supported outlier detection methods.
+var outlierMethods = []string{models.OutlierZScore, models.OutlierIQR, models.OutlierHistory}
+
+// OutlierAction decides what ingestion does with rows whose price deviates from the current price.
+type OutlierAction string
+
+const (
+	// IgnoreOutliers disables the ingestion check.
+	IgnoreOutliers OutlierAction = "off"
+	// FlagOutliers stores deviating rows and reports them.
+	FlagOutliers OutlierAction = "flag"
+	// RejectOutliers leaves deviating rows out and reports them.
+	RejectOutliers OutlierAction = "reject"
+)
+
+// OutlierCheck configures the ingestion-time outlier check. Threshold is the
+// largest accepted deviation from a product's current price, in percent.
+type OutlierCheck struct {
+	Action    OutlierAction
+	Threshold float64
+}
+
+// FindOutliers validates the query and retrieves the outliers via dbKeeper.
+// Methods default to all of them and thresholds to their defaults.
+func (s *MemoryStorage) FindOutliers(ctx context.Context, query models.OutlierQuery) ([]models.Outlier, error) {
+	if len(query.Methods) == 0 {
+		query.Methods = outlierMethods
+	}
+	for _, method := range query.Methods {
+		if !isOutlierMethod(method) {
+			return nil, fmt.Errorf("%w: unknown outlier method %q, expected zscore, iqr or history", ErrInvalidQuery, method)
+		}
+	}
+
+	if query.ZScore == 0 {
+		query.ZScore = defaultZScore
+	}
+	if query.IQRFactor == 0 {
+		query.IQRFactor = defaultIQRFactor
+	}
+	if query.Deviation == 0 {
+		query.Deviation = defaultDeviation
+	}
+	if query.ZScore < 0 || query.IQRFactor < 0 || query.Deviation < 0 {
+		return nil, fmt.Errorf("%w: thresholds must be positive", ErrInvalidQuery)
+	}
+
+	switch {
+	case query.Limit == 0:
+		query.Limit = defaultPageSize
+	case query.Limit < 0 || query.Limit > maxPageSize:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, maxPageSize)
+	}
+
+	return s.keeper.FindOutliers(ctx, query)
+}
+
+// checkOutliers compares the uploaded rows with the current prices of their products.
+// It returns the rows to store and the deviating rows, which are left out when the
+// check rejects them. Rows of products without a current price are never flagged.
+func (s *MemoryStorage) checkOutliers(ctx context.Context, products []models.Product) ([]models.Product, []models.PriceOutlier, error) {
+	if s.outlierCheck.Action != FlagOutliers && s.outlierCheck.Action != RejectOutliers {
+		return products, nil, nil
+	}
+
+	current, err := s.keeper.CurrentPrices(ctx, products)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rejected := s.outlierCheck.Action == RejectOutliers
+	accepted := make([]models.Product, 0, len(products))
+	var outliers []models.PriceOutlier
+	for i, product := range products {
+		if current[i] == nil || *current[i] == 0 {
+			accepted = append(accepted, product)
+			continue
+		}
+
+		deviation := (product.Price - *current[i]) / *current[i] * 100
+		if math.Abs(deviation) <= s.outlierCheck.Threshold {
+			accepted = append(accepted, product)
+			continue
+		}
+
+		outliers = append(outliers, models.PriceOutlier{
+			Row:              i + 1,
+			Name:             product.Name,
+			Category:         product.Category,
+			Price:            product.Price,
+			CurrentPrice:     *current[i],
+			DeviationPercent: math.Round(deviation*100) / 100,
+			Rejected:         rejected,
+		})
+		if !rejected {
+			accepted = append(accepted, product)
+		}
+	}
+	return accepted, outliers, nil
+}
+
+func isOutlierMethod(method string) bool {
+	for _, m := range outlierMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
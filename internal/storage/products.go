@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+)
+
+// GetProduct retrieves a single product via dbKeeper.
+// Deleted products are reported as not found unless includeDeleted is set.
+func (s *MemoryStorage) GetProduct(ctx context.Context, id int, includeDeleted bool) (*models.Product, error) {
+	product, err := s.keeper.GetProduct(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if product.DeletedAt != nil && !includeDeleted {
+		return nil, ErrNotFound
+	}
+	return product, nil
+}
+
+// CreateProduct validates a submitted product and stores it.
+func (s *MemoryStorage) CreateProduct(ctx context.Context, input models.ProductInput) (*models.Product, error) {
+	product, err := s.completeProduct(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return s.keeper.CreateProduct(ctx, product, s.createsCategories())
+}
+
+// ReplaceProduct validates a submitted product and stores it under the given id.
+func (s *MemoryStorage) ReplaceProduct(ctx context.Context, id int, input models.ProductInput) (*models.Product, error) {
+	product, err := s.completeProduct(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	product.ID = id
+	return s.keeper.UpdateProduct(ctx, product, s.createsCategories())
+}
+
+// PatchProduct validates the submitted fields and updates only those.
+func (s *MemoryStorage) PatchProduct(ctx context.Context, id int, input models.ProductInput) (*models.Product, error) {
+	var patch models.ProductPatch
+	patch.Name = input.Name
+	if input.Category != nil {
+		category, err := s.canonicalCategory(ctx, *input.Category)
+		if err != nil {
+			return nil, err
+		}
+		patch.Category = &category
+	}
+
+	if input.Price != nil {
+		price, err := parsePrice(input.Price.String())
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidProduct, err)
+		}
+		patch.Price = &price
+	}
+	if input.CreateDate != nil {
+		createdAt, err := parseCreateDate(*input.CreateDate)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidProduct, err)
+		}
+		patch.CreatedAt = &createdAt
+	}
+
+	return s.keeper.PatchProduct(ctx, id, patch, s.createsCategories())
+}
+
+// DeleteProduct moves a single product to the trash via dbKeeper.
+func (s *MemoryStorage) DeleteProduct(ctx context.Context, id int) error {
+	return s.keeper.DeleteProduct(ctx, id)
+}
+
+// completeProduct validates a product submitted with all of its fields
+// and files it under its canonical category.
+func (s *MemoryStorage) completeProduct(ctx context.Context, input models.ProductInput) (models.Product, error) {
+	switch {
+	case input.Name == nil:
+		return models.Product{}, fmt.Errorf("%w: name is required", ErrInvalidProduct)
+	case input.Category == nil:
+		return models.Product{}, fmt.Errorf("%w: category is required", ErrInvalidProduct)
+	case input.Price == nil:
+		return models.Product{}, fmt.Errorf("%w: price is required", ErrInvalidProduct)
+	case input.CreateDate == nil:
+		return models.Product{}, fmt.Errorf("%w: create_date is required", ErrInvalidProduct)
+	}
+
+	product, err := parseProduct(*input.Name, *input.Category, input.Price.String(), *input.CreateDate)
+	if err != nil {
+		return models.Product{}, fmt.Errorf("%w: %v", ErrInvalidProduct, err)
+	}
+
+	if product.Category, err = s.canonicalCategory(ctx, product.Category); err != nil {
+		return models.Product{}, err
+	}
+	return product, nil
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+)
+
+const (
+	defaultHistogramBuckets = 10
+	maxHistogramBuckets     = 1000
+
+	defaultTopN = 10
+)
+
+// GetStats retrieves the totals of the products matching the filter via dbKeeper.
+func (s *MemoryStorage) GetStats(ctx context.Context, filter models.ProductFilter) (*models.ProcessResponse, error) {
+	return s.keeper.GetStats(ctx, filter)
+}
+
+// GetCategoryStats retrieves price statistics per category via dbKeeper.
+func (s *MemoryStorage) GetCategoryStats(ctx context.Context, filter models.ProductFilter, rollup bool) ([]models.CategoryStats, error) {
+	return s.keeper.GetCategoryStats(ctx, filter, rollup)
+}
+
+// GetHistogram validates the query and retrieves the price distribution via dbKeeper.
+func (s *MemoryStorage) GetHistogram(ctx context.Context, query models.HistogramQuery) (*models.Histogram, error) {
+	if len(query.Edges) > 0 {
+		if query.Buckets != 0 || query.Log {
+			return nil, fmt.Errorf("%w: edges cannot be combined with buckets or log", ErrInvalidQuery)
+		}
+		if len(query.Edges) < 2 {
+			return nil, fmt.Errorf("%w: at least two edges are required", ErrInvalidQuery)
+		}
+		for i := 1; i < len(query.Edges); i++ {
+			if query.Edges[i] <= query.Edges[i-1] {
+				return nil, fmt.Errorf("%w: edges must be strictly increasing", ErrInvalidQuery)
+			}
+		}
+		return s.keeper.GetHistogram(ctx, query)
+	}
+
+	switch {
+	case query.Buckets == 0:
+		query.Buckets = defaultHistogramBuckets
+	case query.Buckets < 1 || query.Buckets > maxHistogramBuckets:
+		return nil, fmt.Errorf("%w: buckets must be between 1 and %d", ErrInvalidQuery, maxHistogramBuckets)
+	}
+	return s.keeper.GetHistogram(ctx, query)
+}
+
+// TopProducts validates the query and retrieves the ranking via dbKeeper.
+func (s *MemoryStorage) TopProducts(ctx context.Context, query models.TopQuery) ([]models.RankedProduct, error) {
+	switch {
+	case query.N == 0:
+		query.N = defaultTopN
+	case query.N < 1 || query.N > maxPageSize:
+		return nil, fmt.Errorf("%w: n must be between 1 and %d", ErrInvalidQuery, maxPageSize)
+	}
+	if query.Per != "" && query.Per != "category" {
+		return nil, fmt.Errorf("%w: unsupported per %q, expected category", ErrInvalidQuery, query.Per)
+	}
+	return s.keeper.TopProducts(ctx, query)
+}
+
+// ComparePeriods validates the periods and retrieves the per-category comparison via dbKeeper.
+func (s *MemoryStorage) ComparePeriods(ctx context.Context, query models.CompareQuery) ([]models.CategoryComparison, error) {
+	if query.Filter.Start != nil || query.Filter.End != nil {
+		return nil, fmt.Errorf("%w: start and end do not apply, the periods are set with a_start, a_end, b_start and b_end", ErrInvalidQuery)
+	}
+	if query.A.End.Before(query.A.Start) || query.B.End.Before(query.B.Start) {
+		return nil, fmt.Errorf("%w: a period cannot end before it starts", ErrInvalidQuery)
+	}
+	return s.keeper.ComparePeriods(ctx, query)
+}
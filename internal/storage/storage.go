@@ -16,8 +16,14 @@ import (
 
 // ErrConflict indicates a data conflict in the store.
 var (
-	ErrConflict = errors.New("data conflict")
-	ErrNotFound = errors.New("not found")
+	ErrConflict        = errors.New("data conflict")
+	ErrNotFound        = errors.New("not found")
+	ErrInvalidProduct  = errors.New("invalid product")
+	ErrInvalidUpdate   = errors.New("invalid update")
+	ErrInvalidCategory = errors.New("invalid category")
+	ErrUnknownCategory = errors.New("unknown category")
+	ErrInvalidBasket   = errors.New("invalid basket")
+	ErrInvalidAlert    = errors.New("invalid alert rule")
 )
 
 // Log defines an interface for logging.
@@ -30,30 +36,89 @@ type Log interface {
 type MemoryStorage struct {
 	ctx context.Context
 
-	keeper Keeper
-	log    Log
+	keeper         Keeper
+	log            Log
+	categoryPolicy CategoryPolicy
+	outlierCheck   OutlierCheck
+	alerts         *alertQueue
 }
 
 // Keeper is an interface for database operations.
 type Keeper interface {
 	GetAllProducts(context.Context) ([]models.Product, error)
-	InsertProducts(context.Context, []models.Product) (*models.ProcessResponse, error)
+	ListProducts(context.Context, models.ListQuery) ([]models.Product, error)
+	StreamProducts(context.Context, models.ProductFilter, func(models.Product) error) error
+	GetProduct(context.Context, int) (*models.Product, error)
+	CreateProduct(context.Context, models.Product, bool) (*models.Product, error)
+	UpdateProduct(context.Context, models.Product, bool) (*models.Product, error)
+	PatchProduct(context.Context, int, models.ProductPatch, bool) (*models.Product, error)
+	DeleteProduct(context.Context, int) error
+	DeleteProducts(context.Context, models.ProductFilter) (int64, error)
+	RestoreProduct(context.Context, int) (*models.Product, error)
+	PurgeDeleted(context.Context, time.Duration) (int64, error)
+	GetPriceHistory(context.Context, int) (*models.PriceHistory, error)
+	UpdateProducts(context.Context, models.ProductFilter, models.BulkUpdate, bool) (int64, error)
+	InsertProducts(context.Context, []models.Product, bool) (*models.ProcessResponse, error)
+	GetStats(context.Context, models.ProductFilter) (*models.ProcessResponse, error)
+	FindOutliers(context.Context, models.OutlierQuery) ([]models.Outlier, error)
+	CurrentPrices(context.Context, []models.Product) ([]*float64, error)
+	GetCategoryStats(context.Context, models.ProductFilter, bool) ([]models.CategoryStats, error)
+	DiffPrices(context.Context, models.DiffQuery) ([]models.PriceChange, error)
+	GetHistogram(context.Context, models.HistogramQuery) (*models.Histogram, error)
+	ComparePeriods(context.Context, models.CompareQuery) ([]models.CategoryComparison, error)
+	TopProducts(context.Context, models.TopQuery) ([]models.RankedProduct, error)
+	GetTimeSeries(context.Context, models.TimeSeriesQuery) ([]models.TimeBucket, error)
+	ListCategories(context.Context) ([]models.Category, error)
+	GetCategory(context.Context, int) (*models.Category, error)
+	CreateCategory(context.Context, models.Category) (*models.Category, error)
+	UpdateCategory(context.Context, models.Category) (*models.Category, error)
+	DeleteCategory(context.Context, int) error
+	ListBaskets(context.Context) ([]models.Basket, error)
+	GetBasket(context.Context, string) (*models.Basket, error)
+	CreateBasket(context.Context, models.Basket) (*models.Basket, error)
+	UpdateBasket(context.Context, string, models.Basket) (*models.Basket, error)
+	DeleteBasket(context.Context, string) error
+	GetPriceIndex(context.Context, models.IndexQuery) (*models.PriceIndex, error)
+	ListAlertRules(context.Context) ([]models.AlertRule, error)
+	GetAlertRule(context.Context, int) (*models.AlertRule, error)
+	CreateAlertRule(context.Context, models.AlertRule) (*models.AlertRule, error)
+	DeleteAlertRule(context.Context, int) error
+	MatchAlerts(context.Context, int) ([]models.AlertMatch, error)
+	ResolveCategories(context.Context, []string) (map[string]string, error)
 	Ping(context.Context) bool
 	Close() bool
 }
 
 // NewMemoryStorage creates a new MemoryStorage instance.
-func NewMemoryStorage(ctx context.Context, keeper Keeper, log Log) *MemoryStorage {
+// Unknown category policies fall back to creating categories and unknown
+// outlier actions disable the outlier check. Alerts are not delivered when notifier is nil.
+func NewMemoryStorage(ctx context.Context, keeper Keeper, log Log, categoryPolicy CategoryPolicy,
+	outlierCheck OutlierCheck, notifier Notifier,
+) *MemoryStorage {
 	if keeper == nil {
 		log.Error("keeper is nil, cannot initialize storage")
 		return nil
 	}
 
+	if categoryPolicy != CreateUnknownCategories && categoryPolicy != RejectUnknownCategories {
+		log.Error("unknown category policy, creating unknown categories", zap.String("policy", string(categoryPolicy)))
+		categoryPolicy = CreateUnknownCategories
+	}
+	switch outlierCheck.Action {
+	case IgnoreOutliers, FlagOutliers, RejectOutliers:
+	default:
+		log.Error("unknown outlier action, disabling the outlier check", zap.String("action", string(outlierCheck.Action)))
+		outlierCheck.Action = IgnoreOutliers
+	}
+
 	return &MemoryStorage{
 		ctx: ctx,
 
-		keeper: keeper,
-		log:    log,
+		keeper:         keeper,
+		log:            log,
+		categoryPolicy: categoryPolicy,
+		outlierCheck:   outlierCheck,
+		alerts:         newAlertQueue(ctx, notifier, log),
 	}
 }
 
@@ -68,6 +133,11 @@ func (s *MemoryStorage) GetAllProducts(ctx context.Context) ([]models.Product, e
 	return products, nil
 }
 
+// StreamProducts passes the products matching the filter to fn one by one via dbKeeper.
+func (s *MemoryStorage) StreamProducts(ctx context.Context, filter models.ProductFilter, fn func(models.Product) error) error {
+	return s.keeper.StreamProducts(ctx, filter, fn)
+}
+
 func (s *MemoryStorage) ProcessPrices(ctx context.Context, data io.Reader) (*models.ProcessResponse, error) {
 	// Read CSV data
 	products, err := s.parseCSV(data)
@@ -75,10 +145,29 @@ func (s *MemoryStorage) ProcessPrices(ctx context.Context, data io.Reader) (*mod
 		return nil, err
 	}
 
-	response, err := s.keeper.InsertProducts(ctx, products)
+	if err := s.canonicalCategories(ctx, products); err != nil {
+		return nil, err
+	}
+
+	products, outliers, err := s.checkOutliers(ctx, products)
+	if err != nil {
+		return nil, err
+	}
+
+	// Nothing left to store, e.g. when every row was rejected, still reports the totals
+	var response *models.ProcessResponse
+	if len(products) == 0 {
+		response, err = s.keeper.GetStats(ctx, models.ProductFilter{})
+	} else {
+		response, err = s.keeper.InsertProducts(ctx, products, s.createsCategories())
+	}
 	if err != nil {
 		return nil, err
 	}
+	response.Outliers = outliers
+	if response.Upload != nil {
+		response.Alerts = s.matchAlerts(ctx, response.Upload.ID)
+	}
 
 	return response, nil
 }
@@ -113,25 +202,50 @@ func (s *MemoryStorage) parseCSV(data io.Reader) ([]models.Product, error) {
 			return nil, fmt.Errorf("invalid ID format: %v", parseErr)
 		}
 
-		// Parse Price
-		price, parseErr := strconv.ParseFloat(record[3], 64)
+		product, parseErr := parseProduct(record[1], record[2], record[3], record[4])
 		if parseErr != nil {
-			return nil, fmt.Errorf("invalid price format: %v", parseErr)
-		}
-
-		// Parse CreatedAt
-		createdAt, dateErr := time.Parse("2006-01-02", record[4])
-		if dateErr != nil {
-			return nil, fmt.Errorf("invalid date format: %v", dateErr)
+			return nil, parseErr
 		}
+		product.ID = id
 
-		products = append(products, models.Product{
-			ID:        id,
-			Name:      record[1],
-			Category:  record[2],
-			Price:     price,
-			CreatedAt: createdAt,
-		})
+		products = append(products, product)
 	}
 	return products, nil
 }
+
+// parseProduct builds a product from the textual fields of an upload record.
+// The same rules apply to products submitted one by one.
+func parseProduct(name, category, price, createDate string) (models.Product, error) {
+	parsedPrice, err := parsePrice(price)
+	if err != nil {
+		return models.Product{}, err
+	}
+
+	createdAt, err := parseCreateDate(createDate)
+	if err != nil {
+		return models.Product{}, err
+	}
+
+	return models.Product{
+		Name:      name,
+		Category:  category,
+		Price:     parsedPrice,
+		CreatedAt: createdAt,
+	}, nil
+}
+
+func parsePrice(raw string) (float64, error) {
+	price, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid price format: %v", err)
+	}
+	return price, nil
+}
+
+func parseCreateDate(raw string) (time.Time, error) {
+	createdAt, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date format: %v", err)
+	}
+	return createdAt, nil
+}
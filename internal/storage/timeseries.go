@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+)
+
+const (
+	defaultInterval = "day"
+	defaultTimeZone = "UTC"
+	maxTimeBuckets  = 5000
+)
+
+// intervals lists the supported time series intervals.
+var intervals = map[string]bool{
+	"day":   true,
+	"week":  true,
+	"month": true,
+}
+
+// GetTimeSeries validates the query and retrieves the bucketed prices via dbKeeper.
+func (s *MemoryStorage) GetTimeSeries(ctx context.Context, query models.TimeSeriesQuery) (*models.TimeSeries, error) {
+	if query.Interval == "" {
+		query.Interval = defaultInterval
+	}
+	if !intervals[query.Interval] {
+		return nil, fmt.Errorf("%w: unsupported interval %q, expected day, week or month", ErrInvalidQuery, query.Interval)
+	}
+
+	if query.TimeZone == "" {
+		query.TimeZone = defaultTimeZone
+	}
+	// Local names the server's zone, which PostgreSQL does not know by that name
+	location, err := time.LoadLocation(query.TimeZone)
+	if err != nil || query.TimeZone == "Local" {
+		return nil, fmt.Errorf("%w: unknown time zone %q", ErrInvalidQuery, query.TimeZone)
+	}
+
+	// One bucket past the maximum tells that the range is too wide
+	query.Limit = maxTimeBuckets
+	buckets, err := s.keeper.GetTimeSeries(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(buckets) > maxTimeBuckets {
+		return nil, fmt.Errorf("%w: the range spans more than %d buckets, narrow it or use a longer interval", ErrInvalidQuery, maxTimeBuckets)
+	}
+
+	// Report bucket starts in the requested time zone
+	for i := range buckets {
+		buckets[i].Start = buckets[i].Start.In(location)
+	}
+	return &models.TimeSeries{
+		Interval: query.Interval,
+		TimeZone: query.TimeZone,
+		Buckets:  buckets,
+	}, nil
+}
@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+	"go.uber.org/zap"
+)
+
+// purgeInterval is how often the trash is checked for expired products.
+const purgeInterval = time.Hour
+
+// RestoreProduct takes a deleted product out of the trash via dbKeeper.
+func (s *MemoryStorage) RestoreProduct(ctx context.Context, id int) (*models.Product, error) {
+	return s.keeper.RestoreProduct(ctx, id)
+}
+
+// PurgeTrash permanently removes products deleted longer than retention ago,
+// checking right away and then every purgeInterval until ctx is done.
+func (s *MemoryStorage) PurgeTrash(ctx context.Context, retention time.Duration) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := s.keeper.PurgeDeleted(ctx, retention)
+		if err != nil {
+			s.log.Error("Failed to purge trash", zap.Error(err))
+		} else if purged > 0 {
+			s.log.Info("Trash purged", zap.Int64("count", purged))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/drstein77/priceanalyzer/internal/models"
+)
+
+const (
+	defaultTrendWindow = 7
+	maxTrendWindow     = 365
+
+	// flatTolerance is the relative change of a moving average below which a trend counts as flat.
+	flatTolerance = 0.01
+)
+
+// GetTrend computes moving averages, rolling extremes and trend directions over the
+// bucketed prices of a product or category.
+func (s *MemoryStorage) GetTrend(ctx context.Context, query models.TrendQuery) (*models.Trend, error) {
+	if query.Filter.ProductID == nil && query.Filter.Category == "" {
+		return nil, fmt.Errorf("%w: product_id or category is required", ErrInvalidQuery)
+	}
+
+	switch {
+	case query.Window == 0:
+		query.Window = defaultTrendWindow
+	case query.Window < 2 || query.Window > maxTrendWindow:
+		return nil, fmt.Errorf("%w: window must be between 2 and %d", ErrInvalidQuery, maxTrendWindow)
+	}
+
+	series, err := s.GetTimeSeries(ctx, models.TimeSeriesQuery{
+		Filter:   query.Filter,
+		Interval: query.Interval,
+		TimeZone: query.TimeZone,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	points := trendPoints(series.Buckets, query.Window)
+	return &models.Trend{
+		Interval:  series.Interval,
+		Window:    query.Window,
+		Direction: overallDirection(points, query.Window),
+		Points:    points,
+	}, nil
+}
+
+// trendPoints computes the indicators of every bucket. Empty buckets carry the
+// previous price forward; buckets before the first price are skipped.
+func trendPoints(buckets []models.TimeBucket, window int) []models.TrendPoint {
+	alpha := 2 / float64(window+1)
+
+	var prices []float64
+	var ema *float64
+	points := []models.TrendPoint{}
+	for _, bucket := range buckets {
+		var price float64
+		switch {
+		case bucket.Avg != nil:
+			price = *bucket.Avg
+		case len(prices) > 0:
+			price = prices[len(prices)-1]
+		default:
+			continue
+		}
+		prices = append(prices, price)
+
+		point := models.TrendPoint{Period: bucket.Start, Price: rounded(price)}
+		if len(prices) >= window {
+			recent := prices[len(prices)-window:]
+			sma := mean(recent)
+			low, high := extremes(recent)
+
+			// The exponential average is seeded with the first simple average
+			if ema == nil {
+				ema = &sma
+			} else {
+				next := alpha*price + (1-alpha)*(*ema)
+				ema = &next
+			}
+
+			point.SMA = rounded(sma)
+			point.EMA = rounded(*ema)
+			point.Min = rounded(low)
+			point.Max = rounded(high)
+			if n := len(points); n > 0 && points[n-1].SMA != nil {
+				point.Direction = direction(*points[n-1].SMA, sma)
+			}
+		}
+		points = append(points, point)
+	}
+	return points
+}
+
+// overallDirection compares the latest moving average with the one a window earlier.
+func overallDirection(points []models.TrendPoint, window int) string {
+	n := len(points)
+	if n == 0 || n-1-window < 0 {
+		return models.TrendFlat
+	}
+	last, earlier := points[n-1].SMA, points[n-1-window].SMA
+	if last == nil || earlier == nil {
+		return models.TrendFlat
+	}
+	return direction(*earlier, *last)
+}
+
+func direction(from, to float64) string {
+	if from == 0 {
+		return models.TrendFlat
+	}
+	switch change := (to - from) / from; {
+	case change > flatTolerance:
+		return models.TrendUp
+	case change < -flatTolerance:
+		return models.TrendDown
+	default:
+		return models.TrendFlat
+	}
+}
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func extremes(values []float64) (float64, float64) {
+	low, high := values[0], values[0]
+	for _, v := range values[1:] {
+		low = math.Min(low, v)
+		high = math.Max(high, v)
+	}
+	return low, high
+}
+
+// rounded rounds to cents.
+func rounded(v float64) *float64 {
+	r := math.Round(v*100) / 100
+	return &r
+}
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS prices_create_date_id_idx;
+DROP INDEX IF EXISTS prices_category_id_idx;
+DROP INDEX IF EXISTS prices_name_id_idx;
+DROP INDEX IF EXISTS prices_price_id_idx;
@@ -0,0 +1,4 @@
+CREATE INDEX IF NOT EXISTS prices_price_id_idx ON prices (price, id);
+CREATE INDEX IF NOT EXISTS prices_name_id_idx ON prices (name, id);
+CREATE INDEX IF NOT EXISTS prices_category_id_idx ON prices (category, id);
+CREATE INDEX IF NOT EXISTS prices_create_date_id_idx ON prices (create_date, id);
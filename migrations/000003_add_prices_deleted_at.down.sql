@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS prices_deleted_at_idx;
+ALTER TABLE prices DROP COLUMN IF EXISTS deleted_at;
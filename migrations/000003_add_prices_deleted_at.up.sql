@@ -0,0 +1,2 @@
+ALTER TABLE prices ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS prices_deleted_at_idx ON prices (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS price_history;
+DROP INDEX IF EXISTS prices_product_id_create_date_idx;
+ALTER TABLE prices DROP COLUMN IF EXISTS product_id;
+DROP TABLE IF EXISTS products;
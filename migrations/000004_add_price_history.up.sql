@@ -0,0 +1,45 @@
+CREATE TABLE IF NOT EXISTS products (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    category TEXT NOT NULL DEFAULT '',
+    UNIQUE (name, category)
+);
+
+INSERT INTO products (name, category)
+SELECT DISTINCT name, COALESCE(category, '')
+FROM prices
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE prices ADD COLUMN IF NOT EXISTS product_id INTEGER REFERENCES products (id);
+
+UPDATE prices
+SET product_id = products.id
+FROM products
+WHERE products.name = prices.name AND products.category = COALESCE(prices.category, '');
+
+ALTER TABLE prices ALTER COLUMN product_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS prices_product_id_create_date_idx ON prices (product_id, create_date);
+
+CREATE TABLE IF NOT EXISTS price_history (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    price NUMERIC(10, 2) NOT NULL,
+    effective_from TIMESTAMP NOT NULL,
+    effective_to TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS price_history_product_id_idx ON price_history (product_id, effective_from);
+
+INSERT INTO price_history (product_id, price, effective_from, effective_to)
+SELECT product_id, price, effective_from,
+       LEAD(effective_from) OVER (PARTITION BY product_id ORDER BY effective_from)
+FROM (
+    SELECT product_id, price, create_date AS effective_from,
+           LAG(price) OVER (PARTITION BY product_id ORDER BY create_date) AS previous_price
+    FROM (
+        SELECT DISTINCT ON (product_id, create_date) product_id, price, create_date
+        FROM prices
+        WHERE deleted_at IS NULL
+        ORDER BY product_id, create_date, id DESC
+    ) daily
+) changes
+WHERE previous_price IS DISTINCT FROM price;
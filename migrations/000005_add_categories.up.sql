@@ -0,0 +1,58 @@
+CREATE TABLE IF NOT EXISTS categories (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    parent_id INTEGER REFERENCES categories (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS categories_name_idx ON categories (LOWER(name));
+CREATE INDEX IF NOT EXISTS categories_parent_id_idx ON categories (parent_id);
+
+CREATE TABLE IF NOT EXISTS category_aliases (
+    alias TEXT PRIMARY KEY,
+    category_id INTEGER NOT NULL REFERENCES categories (id) ON DELETE CASCADE
+);
+
+-- Register the categories already in use, one per spelling that differs only in case
+INSERT INTO categories (name)
+SELECT DISTINCT ON (LOWER(TRIM(category))) TRIM(category)
+FROM prices
+WHERE TRIM(COALESCE(category, '')) <> ''
+ORDER BY LOWER(TRIM(category)), TRIM(category)
+ON CONFLICT DO NOTHING;
+
+-- Rewrite prices to the canonical spelling and relink them to products
+UPDATE prices
+SET category = categories.name
+FROM categories
+WHERE LOWER(TRIM(prices.category)) = LOWER(categories.name) AND prices.category <> categories.name;
+
+INSERT INTO products (name, category)
+SELECT DISTINCT name, COALESCE(category, '')
+FROM prices
+ON CONFLICT DO NOTHING;
+
+UPDATE prices
+SET product_id = products.id
+FROM products
+WHERE products.name = prices.name AND products.category = COALESCE(prices.category, '')
+    AND prices.product_id <> products.id;
+
+DELETE FROM products
+WHERE NOT EXISTS (SELECT 1 FROM prices WHERE prices.product_id = products.id);
+
+-- Rebuild the price history of the merged products
+DELETE FROM price_history;
+
+INSERT INTO price_history (product_id, price, effective_from, effective_to)
+SELECT product_id, price, effective_from,
+       LEAD(effective_from) OVER (PARTITION BY product_id ORDER BY effective_from)
+FROM (
+    SELECT product_id, price, create_date AS effective_from,
+           LAG(price) OVER (PARTITION BY product_id ORDER BY create_date) AS previous_price
+    FROM (
+        SELECT DISTINCT ON (product_id, create_date) product_id, price, create_date
+        FROM prices
+        WHERE deleted_at IS NULL
+        ORDER BY product_id, create_date, id DESC
+    ) daily
+) changes
+WHERE previous_price IS DISTINCT FROM price;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS prices_upload_id_idx;
+ALTER TABLE prices DROP COLUMN IF EXISTS upload_id;
+DROP TABLE IF EXISTS uploads;
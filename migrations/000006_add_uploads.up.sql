@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS uploads (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE prices ADD COLUMN IF NOT EXISTS upload_id INTEGER REFERENCES uploads (id);
+CREATE INDEX IF NOT EXISTS prices_upload_id_idx ON prices (upload_id);
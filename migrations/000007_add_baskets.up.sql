@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS baskets (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS basket_items (
+    basket_id INTEGER NOT NULL REFERENCES baskets (id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    weight NUMERIC NOT NULL CHECK (weight > 0),
+    PRIMARY KEY (basket_id, product_id)
+);
@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS prices_totals_truncate ON prices;
+DROP TRIGGER IF EXISTS prices_totals_delete ON prices;
+DROP TRIGGER IF EXISTS prices_totals_update ON prices;
+DROP TRIGGER IF EXISTS prices_totals_insert ON prices;
+DROP FUNCTION IF EXISTS reset_price_totals();
+DROP FUNCTION IF EXISTS update_price_totals();
+DROP TABLE IF EXISTS price_totals;
+DROP TABLE IF EXISTS category_totals;
@@ -0,0 +1,138 @@
+-- Totals of live prices per category, kept up to date by triggers on prices
+CREATE TABLE IF NOT EXISTS category_totals (
+    category TEXT PRIMARY KEY,
+    items BIGINT NOT NULL,
+    total NUMERIC NOT NULL
+);
+
+-- Totals of all live prices, spread over a fixed number of shards that are summed
+-- on read, so that concurrent writers rarely wait for the same row
+CREATE TABLE IF NOT EXISTS price_totals (
+    shard SMALLINT PRIMARY KEY,
+    items BIGINT NOT NULL DEFAULT 0,
+    categories BIGINT NOT NULL DEFAULT 0,
+    total NUMERIC NOT NULL DEFAULT 0
+);
+
+INSERT INTO category_totals (category, items, total)
+SELECT COALESCE(category, ''), COUNT(*), SUM(price)
+FROM prices
+WHERE deleted_at IS NULL
+GROUP BY 1;
+
+INSERT INTO price_totals (shard, items, categories, total)
+SELECT 0, COALESCE(SUM(items), 0), COUNT(*), COALESCE(SUM(total), 0)
+FROM category_totals;
+
+-- Applies the live rows added and removed by a statement to the totals
+CREATE OR REPLACE FUNCTION update_price_totals() RETURNS trigger AS $$
+DECLARE
+    -- A transaction keeps to the shard of its connection
+    target_shard SMALLINT := pg_backend_pid() % 16;
+    items_delta BIGINT := 0;
+    total_delta NUMERIC := 0;
+    categories_delta BIGINT := 0;
+    rows_items BIGINT;
+    rows_total NUMERIC;
+    changed BIGINT;
+BEGIN
+    -- Lock the touched totals in category order so that concurrent writers cannot deadlock
+    IF TG_OP = 'UPDATE' THEN
+        PERFORM 1 FROM category_totals
+        WHERE category IN (SELECT COALESCE(category, '') FROM old_rows UNION SELECT COALESCE(category, '') FROM new_rows)
+        ORDER BY category
+        FOR UPDATE;
+    ELSIF TG_OP = 'DELETE' THEN
+        PERFORM 1 FROM category_totals
+        WHERE category IN (SELECT COALESCE(category, '') FROM old_rows)
+        ORDER BY category
+        FOR UPDATE;
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        SELECT COUNT(*), COALESCE(SUM(price), 0) INTO rows_items, rows_total
+        FROM new_rows
+        WHERE deleted_at IS NULL;
+        items_delta := items_delta + rows_items;
+        total_delta := total_delta + rows_total;
+
+        -- Rows inserted rather than updated are categories new to the totals
+        WITH upserted AS (
+            INSERT INTO category_totals (category, items, total)
+            SELECT COALESCE(category, ''), COUNT(*), SUM(price)
+            FROM new_rows
+            WHERE deleted_at IS NULL
+            GROUP BY 1
+            ORDER BY 1
+            ON CONFLICT (category) DO UPDATE
+            SET items = category_totals.items + EXCLUDED.items, total = category_totals.total + EXCLUDED.total
+            RETURNING xmax = 0 AS created
+        )
+        SELECT COUNT(*) FILTER (WHERE created) INTO changed FROM upserted;
+        categories_delta := categories_delta + changed;
+    END IF;
+
+    IF TG_OP IN ('DELETE', 'UPDATE') THEN
+        SELECT COUNT(*), COALESCE(SUM(price), 0) INTO rows_items, rows_total
+        FROM old_rows
+        WHERE deleted_at IS NULL;
+        items_delta := items_delta - rows_items;
+        total_delta := total_delta - rows_total;
+
+        UPDATE category_totals
+        SET items = category_totals.items - removed.items, total = category_totals.total - removed.total
+        FROM (
+            SELECT COALESCE(category, '') AS category, COUNT(*) AS items, SUM(price) AS total
+            FROM old_rows
+            WHERE deleted_at IS NULL
+            GROUP BY 1
+        ) removed
+        WHERE category_totals.category = removed.category;
+
+        WITH emptied AS (
+            DELETE FROM category_totals WHERE items <= 0 RETURNING 1
+        )
+        SELECT COUNT(*) INTO changed FROM emptied;
+        categories_delta := categories_delta - changed;
+    END IF;
+
+    IF items_delta <> 0 OR total_delta <> 0 OR categories_delta <> 0 THEN
+        INSERT INTO price_totals AS totals (shard, items, categories, total)
+        VALUES (target_shard, items_delta, categories_delta, total_delta)
+        ON CONFLICT (shard) DO UPDATE
+        SET items = totals.items + EXCLUDED.items,
+            categories = totals.categories + EXCLUDED.categories,
+            total = totals.total + EXCLUDED.total;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Empties the totals along with prices
+CREATE OR REPLACE FUNCTION reset_price_totals() RETURNS trigger AS $$
+BEGIN
+    DELETE FROM category_totals;
+    DELETE FROM price_totals;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER prices_totals_insert
+    AFTER INSERT ON prices
+    REFERENCING NEW TABLE AS new_rows
+    FOR EACH STATEMENT EXECUTE FUNCTION update_price_totals();
+
+CREATE TRIGGER prices_totals_update
+    AFTER UPDATE ON prices
+    REFERENCING OLD TABLE AS old_rows NEW TABLE AS new_rows
+    FOR EACH STATEMENT EXECUTE FUNCTION update_price_totals();
+
+CREATE TRIGGER prices_totals_delete
+    AFTER DELETE ON prices
+    REFERENCING OLD TABLE AS old_rows
+    FOR EACH STATEMENT EXECUTE FUNCTION update_price_totals();
+
+CREATE TRIGGER prices_totals_truncate
+    AFTER TRUNCATE ON prices
+    FOR EACH STATEMENT EXECUTE FUNCTION reset_price_totals();
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    product_id INTEGER REFERENCES products (id) ON DELETE CASCADE,
+    category TEXT,
+    condition TEXT NOT NULL CHECK (condition IN ('above', 'below', 'rise_percent', 'drop_percent')),
+    threshold NUMERIC NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);